@@ -14,4 +14,9 @@ data "jks_keystore" "this" {
       var.intermediate_cert,
     ]
   }
+
+  trusted_certificate {
+    alias       = "ca"
+    certificate = var.ca_cert
+  }
 }
\ No newline at end of file
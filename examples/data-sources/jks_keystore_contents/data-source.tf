@@ -0,0 +1,8 @@
+data "jks_keystore_contents" "vendor" {
+  path     = "${path.module}/vendor.p12"
+  password = var.vendor_keystore_password
+}
+
+output "vendor_certificate" {
+  value = one([for e in data.jks_keystore_contents.vendor.entries : e.certificate_chain[0] if e.type == "private_key"])
+}
\ No newline at end of file
@@ -0,0 +1,4 @@
+data "jks_truststore" "this" {
+  password   = var.truststore_password
+  pem_bundle = file("${path.module}/ca-bundle.pem")
+}
\ No newline at end of file
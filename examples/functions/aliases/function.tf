@@ -0,0 +1,3 @@
+output "aliases" {
+  value = provider::jks::aliases(jks_keystore.this.keystore_base64, var.keystore_password)
+}
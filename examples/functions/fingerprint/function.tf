@@ -0,0 +1,4 @@
+# Pin the server certificate, e.g. in a client configuration
+output "server_cert_sha256" {
+  value = provider::jks::fingerprint(var.server_cert)
+}
@@ -0,0 +1,5 @@
+# Convert an existing JKS keystore for a client that only reads PKCS#12
+output "pkcs12_base64" {
+  value     = provider::jks::jks_to_pkcs12(filebase64("keystore.jks"), var.keystore_password)
+  sensitive = true
+}
@@ -0,0 +1,5 @@
+# Keystore for an application that only reads JKS
+output "keystore_base64" {
+  value     = provider::jks::pem_to_jks(var.server_cert, var.private_key, var.intermediate_cert, var.keystore_password)
+  sensitive = true
+}
@@ -0,0 +1,7 @@
+# Organisation-wide defaults, used by every keystore that doesn't set them.
+# The default password can also be set with the JKS_PASSWORD environment variable.
+provider "jks" {
+  store_type              = "pkcs12"
+  deterministic           = true
+  strict_chain_validation = true
+}
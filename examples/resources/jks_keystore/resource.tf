@@ -0,0 +1,50 @@
+resource "random_password" "keystore" {
+  length = 16
+}
+
+resource "jks_keystore" "this" {
+  password = random_password.keystore.result
+
+  key_pair {
+    alias       = "cert"
+    certificate = var.server_cert
+    private_key = var.private_key
+
+    intermediate_certificates = [
+      var.intermediate_cert,
+    ]
+  }
+
+  trusted_certificate {
+    alias       = "ca"
+    certificate = var.ca_cert
+  }
+}
+
+# Extend the JDK truststore with an internal certificate authority
+resource "jks_keystore" "cacerts" {
+  password               = var.truststore_password
+  store_type             = "pkcs12"
+  base_keystore_path     = "/usr/lib/jvm/java-17-openjdk/lib/security/cacerts"
+  base_keystore_password = "changeit"
+
+  trusted_certificate {
+    alias       = "internal-ca"
+    certificate = var.ca_cert
+  }
+}
+# Keep an AES key alongside TLS material, as keytool -genseckey does
+resource "random_bytes" "aes" {
+  length = 32
+}
+
+resource "jks_keystore" "secrets" {
+  password   = random_password.keystore.result
+  store_type = "pkcs12"
+
+  secret_key {
+    alias      = "encryption-key"
+    algorithm  = "AES"
+    key_base64 = random_bytes.aes.base64
+  }
+}
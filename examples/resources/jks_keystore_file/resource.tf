@@ -0,0 +1,23 @@
+resource "random_password" "keystore" {
+  length = 16
+}
+
+resource "jks_keystore_file" "this" {
+  filename   = "${path.module}/config/keystore.p12"
+  password   = random_password.keystore.result
+  store_type = "pkcs12"
+
+  key_pair {
+    alias       = "cert"
+    certificate = var.server_cert
+    private_key = var.private_key
+
+    intermediate_certificates = [
+      var.intermediate_cert,
+    ]
+  }
+}
+
+output "certificate_expiry" {
+  value = jks_keystore_file.this.entries["cert"].not_after
+}
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func NewAliasesFunction() function.Function {
+	return &AliasesFunction{}
+}
+
+// AliasesFunction defines the function implementation.
+type AliasesFunction struct{}
+
+func (f *AliasesFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "aliases"
+}
+
+func (f *AliasesFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Lists the aliases of a keystore.",
+		MarkdownDescription: "Returns the aliases of all entries in a base 64 encoded keystore, in alias order. The format is detected automatically. Use the `jks_keystore_contents` data source to read the entries themselves.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "keystore_base64",
+				MarkdownDescription: "Base 64 encoded keystore, in JKS, PKCS#12, JCEKS or BouncyCastle format.",
+			},
+			function.StringParameter{
+				Name:                "password",
+				MarkdownDescription: "Password for the keystore & its keys.",
+			},
+		},
+		Return: function.ListReturn{
+			ElementType: types.StringType,
+		},
+	}
+}
+
+func (f *AliasesFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var ksB64, password string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &ksB64, &password))
+	if resp.Error != nil {
+		return
+	}
+
+	ksData, funcErr := decodeKeystoreArgument(0, ksB64)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+	ks, err := jks.Open(ksData, password)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error reading keystore: "+err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, ks.Aliases()))
+}
+
+// decodeKeystoreArgument decodes a base 64 encoded keystore passed as the function argument at a position.
+func decodeKeystoreArgument(position int64, ksB64 string) ([]byte, *function.FuncError) {
+	ksData, err := base64.StdEncoding.DecodeString(ksB64)
+	if err != nil {
+		return nil, function.NewArgumentFuncError(position, "Error decoding keystore: "+err.Error())
+	}
+	return ksData, nil
+}
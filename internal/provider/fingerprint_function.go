@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+func NewFingerprintFunction() function.Function {
+	return &FingerprintFunction{}
+}
+
+// FingerprintFunction defines the function implementation.
+type FingerprintFunction struct{}
+
+func (f *FingerprintFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "fingerprint"
+}
+
+func (f *FingerprintFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Computes the SHA-256 fingerprint of a PEM certificate.",
+		MarkdownDescription: "Returns the lowercase hex SHA-256 fingerprint of the first certificate in a PEM bundle, as in the `entries` attribute of `jks_keystore`. PEM blocks other than certificates are ignored.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "pem",
+				MarkdownDescription: "Certificate in PEM format.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *FingerprintFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var pemData string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &pemData))
+	if resp.Error != nil {
+		return
+	}
+
+	// find first certificate
+	certs, err := jks.ParseCertificatesPEM([]byte(pemData))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error parsing certificate: "+err.Error())
+		return
+	}
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, jks.NewCertificateInfo(certs[0]).SHA256Fingerprint))
+}
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/fhke/terraform-provider-jks/test/util"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runFunction runs f with string arguments, returning the result & any error.
+func runFunction(f function.Function, result attr.Value, args ...string) (attr.Value, *function.FuncError) {
+	values := make([]attr.Value, 0, len(args))
+	for _, arg := range args {
+		values = append(values, types.StringValue(arg))
+	}
+
+	resp := function.RunResponse{Result: function.NewResultData(result)}
+	f.Run(context.TODO(), function.RunRequest{Arguments: function.NewArgumentsData(values)}, &resp)
+	return resp.Result.Value(), resp.Error
+}
+
+// runStringFunction runs f with string arguments, returning the string result & any error.
+func runStringFunction(f function.Function, args ...string) (string, *function.FuncError) {
+	result, funcErr := runFunction(f, types.StringUnknown(), args...)
+	return result.(types.String).ValueString(), funcErr
+}
+
+// openFunctionKeystore decodes & opens a base 64 encoded keystore returned by a function.
+func openFunctionKeystore(t *testing.T, ksB64 string, password string) *jks.Keystore {
+	ksData, err := base64.StdEncoding.DecodeString(ksB64)
+	require.NoError(t, err, "Keystore should be base 64 encoded")
+	ks, err := jks.Open(ksData, password)
+	require.NoError(t, err, "It should open keystore")
+	return ks
+}
+
+func TestPemToJksFunction(t *testing.T) {
+	password := "test2580"
+	leafKey, chain := util.NewCertChainPEM(t, 1)
+	now := time.Now()
+	expiredKey, expiredCrt := util.NewSelfSignedCertPEMWithValidity(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	otherKey, _ := util.NewSelfSignedCertPEM(t)
+
+	// key pair with chain
+	ksB64, funcErr := runStringFunction(NewPemToJksFunction(), string(chain[0]), string(leafKey), string(chain[1]), password)
+	require.Nil(t, funcErr, "It should convert PEM to JKS")
+	ks := openFunctionKeystore(t, ksB64, password)
+	assert.Equal(t, jks.StoreTypeJKS, ks.Type, "Keystore should be JKS")
+	assert.Equal(t, []string{pemToJksAlias}, ks.Aliases(), "Keystore should contain the key pair")
+	assert.Equal(t, chain[:2], ks.Entries[0].CertificateChainPEM(), "Certificate chain should match")
+
+	again, funcErr := runStringFunction(NewPemToJksFunction(), string(chain[0]), string(leafKey), string(chain[1]), password)
+	require.Nil(t, funcErr, "It should convert PEM to JKS")
+	assert.Equal(t, ksB64, again, "Same arguments should produce the same keystore")
+
+	// expired certificate without chain
+	ksB64, funcErr = runStringFunction(NewPemToJksFunction(), string(expiredCrt), string(expiredKey), "", password)
+	require.Nil(t, funcErr, "Expired certificate should be accepted")
+	ks = openFunctionKeystore(t, ksB64, password)
+	assert.Equal(t, [][]byte{expiredCrt}, ks.Entries[0].CertificateChainPEM(), "Certificate should match")
+
+	// mismatched key
+	_, funcErr = runStringFunction(NewPemToJksFunction(), string(expiredCrt), string(otherKey), "", password)
+	require.NotNil(t, funcErr, "Mismatched key should be rejected")
+	assert.Contains(t, funcErr.Text, "Error creating keystore", "Error should describe failure")
+}
+
+func TestJksToPkcs12Function(t *testing.T) {
+	password := "test2580"
+	now := time.Now()
+	expiredKey, expiredCrt := util.NewSelfSignedCertPEMWithValidity(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	_, caCrt := util.NewSelfSignedCertPEM(t)
+
+	ksBuilder := jks.NewKeystoreBuilder()
+	ksBuilder.AddCert("server", expiredCrt, expiredKey)
+	ksBuilder.AddTrustedCert("ca", caCrt)
+	ksBuilder.SetPassword(password)
+	ksBuilder.SetSkipValidityChecks(true)
+	keyStore, err := ksBuilder.Build()
+	require.NoError(t, err, "It should build keystore")
+	ksB64 := base64.StdEncoding.EncodeToString(keyStore)
+
+	p12B64, funcErr := runStringFunction(NewJksToPkcs12Function(), ksB64, password)
+	require.Nil(t, funcErr, "It should convert JKS to PKCS#12")
+	ks := openFunctionKeystore(t, p12B64, password)
+	assert.Equal(t, jks.StoreTypePKCS12, ks.Type, "Keystore should be PKCS#12")
+	assert.Equal(t, []string{"ca", "server"}, ks.Aliases(), "Keystore should contain every entry")
+	assert.Equal(t, [][]byte{expiredCrt}, ks.Entries[1].CertificateChainPEM(), "Certificate should match")
+	assert.NotNil(t, ks.Entries[1].PrivateKey, "Private key should be kept")
+
+	again, funcErr := runStringFunction(NewJksToPkcs12Function(), ksB64, password)
+	require.Nil(t, funcErr, "It should convert JKS to PKCS#12")
+	assert.Equal(t, p12B64, again, "Same arguments should produce the same keystore")
+
+	// invalid arguments
+	_, funcErr = runStringFunction(NewJksToPkcs12Function(), "not base 64", password)
+	require.NotNil(t, funcErr, "Invalid base 64 should be rejected")
+	assert.Equal(t, int64(0), *funcErr.FunctionArgument, "Error should be for keystore argument")
+	_, funcErr = runStringFunction(NewJksToPkcs12Function(), ksB64, "wrong")
+	require.NotNil(t, funcErr, "Wrong password should be rejected")
+	assert.Contains(t, funcErr.Text, "Error reading keystore", "Error should describe failure")
+}
+
+func TestAliasesFunction(t *testing.T) {
+	password := "test2580"
+	key, crt := util.NewSelfSignedCertPEM(t)
+	_, caCrt := util.NewSelfSignedCertPEM(t)
+
+	ksBuilder := jks.NewKeystoreBuilder()
+	ksBuilder.AddCert("server", crt, key)
+	ksBuilder.AddTrustedCert("ca", caCrt)
+	ksBuilder.SetPassword(password)
+	ksBuilder.SetStoreType(jks.StoreTypePKCS12)
+	keyStore, err := ksBuilder.Build()
+	require.NoError(t, err, "It should build keystore")
+
+	result, funcErr := runFunction(NewAliasesFunction(), types.ListUnknown(types.StringType), base64.StdEncoding.EncodeToString(keyStore), password)
+	require.Nil(t, funcErr, "It should list aliases")
+	var aliases []string
+	require.False(t, result.(types.List).ElementsAs(context.TODO(), &aliases, false).HasError(), "Result should be a list of strings")
+	assert.Equal(t, []string{"ca", "server"}, aliases, "Aliases should match")
+
+	_, funcErr = runFunction(NewAliasesFunction(), types.ListUnknown(types.StringType), base64.StdEncoding.EncodeToString([]byte("not a keystore")), password)
+	require.NotNil(t, funcErr, "Invalid keystore should be rejected")
+	assert.Equal(t, int64(0), *funcErr.FunctionArgument, "Error should be for keystore argument")
+}
+
+func TestFingerprintFunction(t *testing.T) {
+	key, crt := util.NewSelfSignedCertPEM(t)
+	block, _ := pem.Decode(crt)
+	sum := sha256.Sum256(block.Bytes)
+
+	// certificate after private key
+	fingerprint, funcErr := runStringFunction(NewFingerprintFunction(), string(key)+string(crt))
+	require.Nil(t, funcErr, "It should compute fingerprint")
+	assert.Equal(t, hex.EncodeToString(sum[:]), fingerprint, "Fingerprint should match")
+
+	_, funcErr = runStringFunction(NewFingerprintFunction(), string(key))
+	require.NotNil(t, funcErr, "PEM without certificate should be rejected")
+	assert.Equal(t, int64(0), *funcErr.FunctionArgument, "Error should be for PEM argument")
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+func NewJksToPkcs12Function() function.Function {
+	return &JksToPkcs12Function{}
+}
+
+// JksToPkcs12Function defines the function implementation.
+// Functions must return the same result for the same arguments, so keystores are always built deterministically &
+// without time-based validity checks.
+type JksToPkcs12Function struct{}
+
+func (f *JksToPkcs12Function) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "jks_to_pkcs12"
+}
+
+func (f *JksToPkcs12Function) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Converts a keystore to PKCS#12 format.",
+		MarkdownDescription: "Converts a base 64 encoded JKS keystore, or a keystore in any other supported format, to PKCS#12 & returns it base 64 encoded. Every entry is kept, protected with the same password. The keystore is built deterministically, so the same arguments always produce the same keystore, and certificates are not checked for expiry.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "keystore_base64",
+				MarkdownDescription: "Base 64 encoded keystore, in JKS, PKCS#12, JCEKS or BouncyCastle format.",
+			},
+			function.StringParameter{
+				Name:                "password",
+				MarkdownDescription: "Password for the keystore & its keys.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *JksToPkcs12Function) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var ksB64, password string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &ksB64, &password))
+	if resp.Error != nil {
+		return
+	}
+
+	ksData, funcErr := decodeKeystoreArgument(0, ksB64)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+
+	// rebuild keystore as PKCS#12
+	bld := jks.NewKeystoreBuilder()
+	if err := bld.AddKeystore(ksData, password); err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "Error reading keystore: "+err.Error())
+		return
+	}
+	bld.SetPassword(password)
+	bld.SetStoreType(jks.StoreTypePKCS12)
+	bld.SetDeterministic(true)
+	bld.SetSkipValidityChecks(true)
+	p12Data, err := bld.Build()
+	if err != nil {
+		resp.Error = function.NewFuncError("Error creating PKCS#12 keystore: " + err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, base64.StdEncoding.EncodeToString(p12Data)))
+}
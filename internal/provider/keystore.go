@@ -0,0 +1,570 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// KeystoreModel describes the data model shared by the keystore data source & resource.
+type KeystoreModel struct {
+	// Input values
+	KeyPair            types.Set    `tfsdk:"key_pair"`
+	TrustedCertificate types.Set    `tfsdk:"trusted_certificate"`
+	SecretKey          types.Set    `tfsdk:"secret_key"`
+	Password           types.String `tfsdk:"password"`
+	StoreType          types.String `tfsdk:"store_type"`
+	Deterministic      types.Bool   `tfsdk:"deterministic"`
+	BuildChains        types.Bool   `tfsdk:"build_certificate_chains"`
+	MinValidity        types.String `tfsdk:"min_remaining_validity"`
+	// Base keystore values
+	BaseKeystoreB64      types.String `tfsdk:"base_keystore_base64"`
+	BaseKeystorePath     types.String `tfsdk:"base_keystore_path"`
+	BaseKeystorePassword types.String `tfsdk:"base_keystore_password"`
+	RemoveAliases        types.Set    `tfsdk:"remove_aliases"`
+	// Computed values
+	KeystoreB64 types.String `tfsdk:"keystore_base64"`
+	JksB64      types.String `tfsdk:"jks_base64"`
+	Pkcs12B64   types.String `tfsdk:"pkcs12_base64"`
+	Entries     types.Map    `tfsdk:"entries"`
+}
+
+// keepComputed copies the computed values from prior, for updates that don't change the keystore.
+func (m *KeystoreModel) keepComputed(prior *KeystoreModel) {
+	m.KeystoreB64 = prior.KeystoreB64
+	m.JksB64 = prior.JksB64
+	m.Pkcs12B64 = prior.Pkcs12B64
+	m.Entries = prior.Entries
+}
+
+// KeystoreEntryModel describes the certificate metadata of a keystore entry, or the key metadata of a secret key entry.
+type KeystoreEntryModel struct {
+	Type                    types.String   `tfsdk:"type"`
+	SHA1Fingerprint         types.String   `tfsdk:"sha1_fingerprint"`
+	SHA256Fingerprint       types.String   `tfsdk:"sha256_fingerprint"`
+	Subject                 types.String   `tfsdk:"subject"`
+	Issuer                  types.String   `tfsdk:"issuer"`
+	SerialNumber            types.String   `tfsdk:"serial_number"`
+	NotBefore               types.String   `tfsdk:"not_before"`
+	NotAfter                types.String   `tfsdk:"not_after"`
+	SubjectAlternativeNames []types.String `tfsdk:"subject_alternative_names"`
+	KeyAlgorithm            types.String   `tfsdk:"key_algorithm"`
+	KeySize                 types.Int64    `tfsdk:"key_size"`
+}
+
+// keystoreEntryType is the object type of KeystoreEntryModel.
+var keystoreEntryType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"type":                      types.StringType,
+		"sha1_fingerprint":          types.StringType,
+		"sha256_fingerprint":        types.StringType,
+		"subject":                   types.StringType,
+		"issuer":                    types.StringType,
+		"serial_number":             types.StringType,
+		"not_before":                types.StringType,
+		"not_after":                 types.StringType,
+		"subject_alternative_names": types.ListType{ElemType: types.StringType},
+		"key_algorithm":             types.StringType,
+		"key_size":                  types.Int64Type,
+	},
+}
+
+// build generates the keystore described by the model & stores it in the computed attributes. Key passwords &
+// chain validation fall back to the provider defaults, which may be nil.
+func (m *KeystoreModel) build(ctx context.Context, defaults *JksProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// check aliases again, as they may have been unknown during validation
+	diags.Append(m.validateAliases()...)
+	if diags.HasError() {
+		return diags
+	}
+
+	// create jks builder
+	bld := jks.NewKeystoreBuilder()
+
+	// set store password
+	bld.SetPassword(m.Password.ValueString())
+
+	// set store format
+	if m.StoreType.IsNull() || m.StoreType.IsUnknown() {
+		m.StoreType = types.StringValue(string(jks.StoreTypeJKS))
+	}
+	storeType := jks.StoreType(m.StoreType.ValueString())
+	bld.SetStoreType(storeType)
+
+	// enable reproducible output
+	bld.SetDeterministic(m.Deterministic.ValueBool())
+
+	// order key pair certificate chains
+	bld.SetBuildChains(m.BuildChains.ValueBool())
+
+	// start from base keystore
+	diags.Append(m.addBaseKeystore(bld)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	keyPasswords := make(map[string]string)
+	for _, kpElem := range m.KeyPair.Elements() {
+		keyPair := kpElem.(types.Object).Attributes()
+
+		// get intermediate certs as [][]byte
+		caCerts := keyPairCACerts(keyPair)
+
+		// Add cert to store
+		alias := keyPair["alias"].(types.String).ValueString()
+		bld.AddCert(
+			alias,
+			[]byte(keyPair["certificate"].(types.String).ValueString()),
+			[]byte(keyPair["private_key"].(types.String).ValueString()),
+			caCerts...,
+		)
+
+		// set password for encrypted private key
+		if keyPassword := keyPair["private_key_password"].(types.String); !keyPassword.IsNull() {
+			bld.SetPrivateKeyPassword(alias, keyPassword.ValueString())
+		}
+
+		// set password protecting private key in keystore
+		if keyPassword := defaults.keyPassword(keyPair["key_password"].(types.String)); keyPassword != "" {
+			bld.SetKeyPassword(alias, keyPassword)
+			keyPasswords[alias] = keyPassword
+		}
+
+		// verify certificate chain, non-strict verification is only a plan-time warning, see validateKeyPairs
+		if roots := keyPair["trusted_roots"].(types.String); !roots.IsNull() && defaults.isStrict(keyPair["strict_chain_validation"].(types.Bool)) {
+			bld.SetTrustedRoots(alias, []byte(roots.ValueString()))
+		}
+	}
+
+	for _, tcElem := range m.TrustedCertificate.Elements() {
+		trustedCert := tcElem.(types.Object).Attributes()
+
+		// Add trusted cert to store
+		bld.AddTrustedCert(
+			trustedCert["alias"].(types.String).ValueString(),
+			[]byte(trustedCert["certificate"].(types.String).ValueString()),
+		)
+	}
+
+	for _, skElem := range m.SecretKey.Elements() {
+		secretKey := skElem.(types.Object).Attributes()
+
+		// Add secret key to store
+		alias := secretKey["alias"].(types.String).ValueString()
+		key, err := base64.StdEncoding.DecodeString(secretKey["key_base64"].(types.String).ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("secret_key"),
+				"Error decoding secret key",
+				fmt.Sprintf("Secret key %q: %s", alias, err),
+			)
+			return diags
+		}
+		bld.AddSecretKey(alias, secretKey["algorithm"].(types.String).ValueString(), key)
+
+		// set password protecting secret key in keystore
+		if keyPassword := defaults.keyPassword(secretKey["key_password"].(types.String)); keyPassword != "" {
+			bld.SetKeyPassword(alias, keyPassword)
+			keyPasswords[alias] = keyPassword
+		}
+	}
+
+	// build keystore
+	ksData, err := bld.Build()
+	if err != nil {
+		diags.AddError(
+			"Error creating keystore",
+			err.Error(),
+		)
+		return diags
+	}
+
+	// read back keystore to add entry metadata to model
+	ks, err := jks.OpenWithKeyPasswords(ksData, m.Password.ValueString(), keyPasswords)
+	if err != nil {
+		diags.AddError(
+			"Error reading keystore",
+			err.Error(),
+		)
+		return diags
+	}
+	entries := make(map[string]KeystoreEntryModel, len(ks.Entries))
+	for _, entry := range ks.Entries {
+		entries[entry.Alias] = newKeystoreEntryModel(entry)
+	}
+	var entryDiags diag.Diagnostics
+	m.Entries, entryDiags = types.MapValueFrom(ctx, keystoreEntryType, entries)
+	diags.Append(entryDiags...)
+
+	// base64 encode keystore & add to model, with format specific attributes only set for their format
+	ksB64 := types.StringValue(base64.StdEncoding.EncodeToString(ksData))
+	m.KeystoreB64 = ksB64
+	m.JksB64 = types.StringNull()
+	m.Pkcs12B64 = types.StringNull()
+	switch storeType {
+	case jks.StoreTypeJKS:
+		m.JksB64 = ksB64
+	case jks.StoreTypePKCS12:
+		m.Pkcs12B64 = ksB64
+	}
+
+	return diags
+}
+
+// newKeystoreEntryModel converts the certificate metadata of a keystore entry to its model. Secret key entries
+// have no certificate, so only their key metadata is set.
+func newKeystoreEntryModel(entry *jks.Entry) KeystoreEntryModel {
+	info := entry.CertificateInfo()
+	if info == nil {
+		return KeystoreEntryModel{
+			Type:                    types.StringValue(string(entry.Type)),
+			SHA1Fingerprint:         types.StringNull(),
+			SHA256Fingerprint:       types.StringNull(),
+			Subject:                 types.StringNull(),
+			Issuer:                  types.StringNull(),
+			SerialNumber:            types.StringNull(),
+			NotBefore:               types.StringNull(),
+			NotAfter:                types.StringNull(),
+			SubjectAlternativeNames: []types.String{},
+			KeyAlgorithm:            types.StringValue(entry.SecretKeyAlgorithm),
+			KeySize:                 types.Int64Value(int64(len(entry.SecretKey) * 8)),
+		}
+	}
+	model := KeystoreEntryModel{
+		Type:              types.StringValue(string(entry.Type)),
+		SHA1Fingerprint:   types.StringValue(info.SHA1Fingerprint),
+		SHA256Fingerprint: types.StringValue(info.SHA256Fingerprint),
+		Subject:           types.StringValue(info.Subject),
+		Issuer:            types.StringValue(info.Issuer),
+		SerialNumber:      types.StringValue(info.SerialNumber),
+		NotBefore:         types.StringValue(info.NotBefore.Format(time.RFC3339)),
+		NotAfter:          types.StringValue(info.NotAfter.Format(time.RFC3339)),
+		KeyAlgorithm:      types.StringValue(info.KeyAlgorithm),
+		KeySize:           types.Int64Value(int64(info.KeySize)),
+	}
+	model.SubjectAlternativeNames = make([]types.String, 0, len(info.SubjectAlternativeNames))
+	for _, name := range info.SubjectAlternativeNames {
+		model.SubjectAlternativeNames = append(model.SubjectAlternativeNames, types.StringValue(name))
+	}
+	return model
+}
+
+// applyDefaults sets the password, store type & deterministic attributes to the provider defaults where they're not
+// set, which may be nil. A password must be set by either.
+func (m *KeystoreModel) applyDefaults(defaults *JksProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	m.Password = defaults.password(m.Password)
+	m.StoreType = defaults.storeType(m.StoreType)
+	m.Deterministic = defaults.deterministic(m.Deterministic)
+	diags.Append(requirePassword(m.Password)...)
+
+	return diags
+}
+
+// validateMinValidity checks that the minimum remaining validity is a valid duration.
+func (m *KeystoreModel) validateMinValidity() diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if m.MinValidity.IsNull() || m.MinValidity.IsUnknown() {
+		return diags
+	}
+	if minValidity, err := time.ParseDuration(m.MinValidity.ValueString()); err != nil {
+		diags.AddAttributeError(
+			path.Root("min_remaining_validity"),
+			"Invalid minimum remaining validity",
+			err.Error(),
+		)
+	} else if minValidity < 0 {
+		diags.AddAttributeError(
+			path.Root("min_remaining_validity"),
+			"Invalid minimum remaining validity",
+			"Minimum remaining validity must not be negative.",
+		)
+	}
+
+	return diags
+}
+
+// validateAliases checks the alias of each key pair, trusted certificate & secret key, and that no aliases collide. Aliases
+// are compared case-insensitively, as Java keystores lowercase them. Unknown aliases are skipped.
+func (m *KeystoreModel) validateAliases() diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	seen := make(map[string]string)
+	for _, block := range []struct {
+		name string
+		set  types.Set
+	}{
+		{"key_pair", m.KeyPair},
+		{"trusted_certificate", m.TrustedCertificate},
+		{"secret_key", m.SecretKey},
+	} {
+		for _, elem := range block.set.Elements() {
+			obj := elem.(types.Object)
+			if obj.IsUnknown() {
+				continue
+			}
+			alias := obj.Attributes()["alias"].(types.String)
+			if alias.IsUnknown() {
+				continue
+			}
+
+			if err := jks.ValidateAlias(alias.ValueString()); err != nil {
+				diags.AddAttributeError(path.Root(block.name), "Invalid alias", err.Error())
+				continue
+			}
+
+			normalized := jks.NormalizeAlias(alias.ValueString())
+			if prev, ok := seen[normalized]; ok {
+				detail := fmt.Sprintf("Alias %q is used by more than one entry.", alias.ValueString())
+				if prev != alias.ValueString() {
+					detail = fmt.Sprintf("Aliases %q & %q collide, as keystore aliases are case-insensitive.", prev, alias.ValueString())
+				}
+				diags.AddAttributeError(path.Root(block.name), "Duplicate alias", detail)
+				continue
+			}
+			seen[normalized] = alias.ValueString()
+		}
+	}
+
+	return diags
+}
+
+// validateKeyPairs checks that the certificate & private key of each key pair match, checks certificate validity
+// periods & verifies certificate chains against any trusted roots, so that invalid key pairs & certificates that
+// expire within min_remaining_validity are reported at plan time. Chains that fail verification are warnings in
+// non-strict mode. Key pairs with unknown values are skipped, as are chains whose strictness depends on provider
+// defaults that aren't configured yet.
+func (m *KeystoreModel) validateKeyPairs(defaults *JksProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for _, kpElem := range m.KeyPair.Elements() {
+		kpObj := kpElem.(types.Object)
+		if kpObj.IsUnknown() {
+			continue
+		}
+		keyPair := kpObj.Attributes()
+
+		alias := keyPair["alias"].(types.String)
+		cert := keyPair["certificate"].(types.String)
+		key := keyPair["private_key"].(types.String)
+		keyPassword := keyPair["private_key_password"].(types.String)
+		if alias.IsUnknown() || cert.IsUnknown() || key.IsUnknown() || keyPassword.IsUnknown() {
+			continue
+		}
+
+		if err := jks.CheckKeyPair(
+			alias.ValueString(),
+			[]byte(cert.ValueString()),
+			[]byte(key.ValueString()),
+			keyPassword.ValueString(),
+		); err != nil {
+			diags.AddAttributeError(
+				path.Root("key_pair"),
+				"Invalid key pair",
+				err.Error(),
+			)
+			continue
+		}
+
+		// check certificate validity, warning about certificates that expire within min_remaining_validity
+		caCerts := keyPair["intermediate_certificates"].(types.List)
+		chain := keyPair["certificate_chain"].(types.String)
+		if !isKnownList(caCerts) || chain.IsUnknown() || m.BuildChains.IsUnknown() || m.MinValidity.IsUnknown() {
+			continue
+		}
+		bld := jks.NewKeystoreBuilder()
+		bld.SetBuildChains(m.BuildChains.ValueBool())
+		if minValidity, err := time.ParseDuration(m.MinValidity.ValueString()); err == nil {
+			// invalid durations are reported by validateMinValidity
+			bld.SetMinRemainingValidity(minValidity)
+		}
+		bld.AddCert(alias.ValueString(), []byte(cert.ValueString()), []byte(key.ValueString()), keyPairCACerts(keyPair)...)
+		warnings, err := bld.CheckValidity(alias.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("key_pair"), "Invalid certificate", err.Error())
+			continue
+		}
+		for _, warning := range warnings {
+			diags.AddAttributeWarning(path.Root("key_pair"), "Certificate expires soon", warning.Error())
+		}
+
+		// verify certificate chain
+		roots := keyPair["trusted_roots"].(types.String)
+		strict := keyPair["strict_chain_validation"].(types.Bool)
+		if roots.IsNull() || roots.IsUnknown() || strict.IsUnknown() {
+			continue
+		}
+		if strict.IsNull() && defaults == nil {
+			continue
+		}
+		if err := bld.VerifyChain(alias.ValueString(), []byte(roots.ValueString())); err != nil {
+			if defaults.isStrict(strict) {
+				diags.AddAttributeError(path.Root("key_pair"), "Invalid certificate chain", err.Error())
+			} else {
+				diags.AddAttributeWarning(path.Root("key_pair"), "Invalid certificate chain", err.Error())
+			}
+		}
+	}
+
+	return diags
+}
+
+// validateSecretKeys checks that the store type supports secret keys, and that each secret key has a supported
+// algorithm & a valid key length. Secret keys with unknown values are skipped, as is the store type check while it
+// depends on provider defaults that aren't configured yet.
+func (m *KeystoreModel) validateSecretKeys(defaults *JksProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if len(m.SecretKey.Elements()) == 0 {
+		return diags
+	}
+	storeType := m.StoreType
+	if storeType.IsNull() && defaults != nil {
+		storeType = defaults.storeType(storeType)
+	}
+	if st := storeType.ValueString(); isStoreType(st) && !jks.StoreType(st).SupportsSecretKeys() {
+		diags.AddAttributeError(
+			path.Root("secret_key"),
+			"Unsupported secret key",
+			fmt.Sprintf("Keystores of store type %s cannot hold secret keys, use a store_type of %s instead.", st, secretKeyStoreTypes()),
+		)
+	}
+
+	for _, skElem := range m.SecretKey.Elements() {
+		skObj := skElem.(types.Object)
+		if skObj.IsUnknown() {
+			continue
+		}
+		secretKey := skObj.Attributes()
+
+		alias := secretKey["alias"].(types.String)
+		algorithm := secretKey["algorithm"].(types.String)
+		keyB64 := secretKey["key_base64"].(types.String)
+		if alias.IsUnknown() || algorithm.IsUnknown() || keyB64.IsUnknown() {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(keyB64.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("secret_key"),
+				"Invalid secret key",
+				fmt.Sprintf("Secret key %q is not valid base 64: %s", alias.ValueString(), err),
+			)
+			continue
+		}
+		if err := jks.CheckSecretKey(alias.ValueString(), algorithm.ValueString(), key); err != nil {
+			diags.AddAttributeError(
+				path.Root("secret_key"),
+				"Invalid secret key",
+				err.Error(),
+			)
+		}
+	}
+
+	return diags
+}
+
+// addBaseKeystore adds the entries of the base keystore to the builder, then removes any aliases listed for removal.
+func (m *KeystoreModel) addBaseKeystore(bld *jks.KeystoreBuilder) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// load base keystore
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case !m.BaseKeystoreB64.IsNull() && !m.BaseKeystorePath.IsNull():
+		diags.AddAttributeError(
+			path.Root("base_keystore_base64"),
+			"Invalid base keystore",
+			"Only one of base_keystore_base64 or base_keystore_path may be set.",
+		)
+		return diags
+	case !m.BaseKeystorePath.IsNull():
+		if data, err = os.ReadFile(m.BaseKeystorePath.ValueString()); err != nil {
+			diags.AddAttributeError(
+				path.Root("base_keystore_path"),
+				"Error reading base keystore file",
+				err.Error(),
+			)
+			return diags
+		}
+	case !m.BaseKeystoreB64.IsNull():
+		if data, err = base64.StdEncoding.DecodeString(m.BaseKeystoreB64.ValueString()); err != nil {
+			diags.AddAttributeError(
+				path.Root("base_keystore_base64"),
+				"Error decoding base keystore",
+				err.Error(),
+			)
+			return diags
+		}
+	}
+
+	// add base keystore entries, with the store password unless a base keystore password is set
+	if data != nil {
+		password := m.Password.ValueString()
+		if !m.BaseKeystorePassword.IsNull() {
+			password = m.BaseKeystorePassword.ValueString()
+		}
+		if err := bld.AddKeystore(data, password); err != nil {
+			diags.AddError(
+				"Error reading base keystore",
+				err.Error(),
+			)
+			return diags
+		}
+	}
+
+	// remove entries
+	for _, aliasElem := range m.RemoveAliases.Elements() {
+		bld.RemoveEntry(aliasElem.(types.String).ValueString())
+	}
+
+	return diags
+}
+
+// isKnownList reports whether a list & all of its elements are known.
+func isKnownList(list types.List) bool {
+	if list.IsUnknown() {
+		return false
+	}
+	for _, elem := range list.Elements() {
+		if elem.IsUnknown() {
+			return false
+		}
+	}
+	return true
+}
+
+// keyPairCACerts returns the CA certificates of a key pair, from its intermediate certificates followed by its
+// certificate chain bundle.
+func keyPairCACerts(keyPair map[string]attr.Value) [][]byte {
+	caCerts := stringListBytes(keyPair["intermediate_certificates"].(types.List))
+	if chain := keyPair["certificate_chain"].(types.String); chain.ValueString() != "" {
+		caCerts = append(caCerts, []byte(chain.ValueString()))
+	}
+	return caCerts
+}
+
+// stringListBytes converts the elements of a list of strings to byte slices.
+func stringListBytes(list types.List) [][]byte {
+	out := make([][]byte, 0, len(list.Elements()))
+	for _, elem := range list.Elements() {
+		out = append(out, []byte(elem.(types.String).ValueString()))
+	}
+	return out
+}
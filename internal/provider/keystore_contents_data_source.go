@@ -0,0 +1,259 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"time"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func NewKeystoreContentsDataSource() datasource.DataSource {
+	return &KeystoreContentsDataSource{}
+}
+
+// KeystoreContentsDataSource defines the data source implementation.
+type KeystoreContentsDataSource struct {
+	// defaults are the provider defaults, or nil if the provider is not configured yet.
+	defaults *JksProviderModel
+}
+
+// KeystoreContentsDataSourceModel describes the data source data model.
+type KeystoreContentsDataSourceModel struct {
+	// Input values
+	KeystoreB64  types.String `tfsdk:"keystore_base64"`
+	Path         types.String `tfsdk:"path"`
+	Password     types.String `tfsdk:"password"`
+	KeyPasswords types.Map    `tfsdk:"key_passwords"`
+	// Computed values
+	StoreType types.String                 `tfsdk:"store_type"`
+	Aliases   types.List                   `tfsdk:"aliases"`
+	Entries   []KeystoreContentsEntryModel `tfsdk:"entries"`
+}
+
+// KeystoreContentsEntryModel describes a single keystore entry.
+type KeystoreContentsEntryModel struct {
+	Alias            types.String   `tfsdk:"alias"`
+	Type             types.String   `tfsdk:"type"`
+	Created          types.String   `tfsdk:"created"`
+	CertificateChain []types.String `tfsdk:"certificate_chain"`
+	PrivateKey       types.String   `tfsdk:"private_key"`
+	SecretKeyAlg     types.String   `tfsdk:"secret_key_algorithm"`
+	SecretKeyB64     types.String   `tfsdk:"secret_key_base64"`
+}
+
+func (d *KeystoreContentsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_keystore_contents"
+}
+
+func (d *KeystoreContentsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	var diags diag.Diagnostics
+	d.defaults, diags = providerDefaults(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (d *KeystoreContentsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads the entries of an existing keystore in JKS, PKCS#12, JCEKS or BouncyCastle format. The format is detected automatically.",
+
+		Attributes: map[string]schema.Attribute{
+			"keystore_base64": schema.StringAttribute{
+				Description: "Base 64 encoded keystore, e.g. `jks_keystore.example.keystore_base64`. Exactly one of `keystore_base64` or `path` must be set.",
+				Optional:    true,
+			},
+			"path": schema.StringAttribute{
+				Description: "Path to a keystore file. Exactly one of `keystore_base64` or `path` must be set.",
+				Optional:    true,
+			},
+			"password": schema.StringAttribute{
+				Description: "Password for keystore, and private & secret keys without a password in `key_passwords`. Defaults to the provider `password`, one of which must be set.",
+				Optional:    true,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"key_passwords": schema.MapAttribute{
+				Description: "Passwords for private & secret keys protected by a password other than the keystore password, by alias, e.g. `{ server = \"key-secret\" }`. Aliases are compared case-insensitively.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"store_type": schema.StringAttribute{
+				Description: "Detected format of the keystore, one of `jks`, `pkcs12`, `jceks`, `bks`, `bks-v1` or `uber`.",
+				Computed:    true,
+			},
+			"aliases": schema.ListAttribute{
+				Description: "Aliases of all entries in the keystore, in alias order.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"entries": schema.ListNestedAttribute{
+				Description: "Entries in the keystore, in alias order.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"alias": schema.StringAttribute{
+							Description: "Alias of entry.",
+							Computed:    true,
+						},
+						"type": schema.StringAttribute{
+							Description: "Type of entry, one of `private_key`, `trusted_certificate` or `secret_key`.",
+							Computed:    true,
+						},
+						"created": schema.StringAttribute{
+							Description: "Creation date of entry in RFC 3339 format. Not set for PKCS#12 keystores, which do not record creation dates.",
+							Computed:    true,
+						},
+						"certificate_chain": schema.ListAttribute{
+							Description: "Certificate chain of a private key entry in PEM format, starting with the leaf certificate, or the certificate of a trusted certificate entry.",
+							ElementType: types.StringType,
+							Computed:    true,
+						},
+						"private_key": schema.StringAttribute{
+							Description: "Private key of a private key entry in PKCS#8 PEM format. Not set for other entry types.",
+							Computed:    true,
+							Sensitive:   true,
+						},
+						"secret_key_algorithm": schema.StringAttribute{
+							Description: "Java algorithm name of a secret key entry, e.g. `AES`. Not set for other entry types.",
+							Computed:    true,
+						},
+						"secret_key_base64": schema.StringAttribute{
+							Description: "Base 64 encoded key material of a secret key entry. Not set for other entry types.",
+							Computed:    true,
+							Sensitive:   true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *KeystoreContentsDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var data KeystoreContentsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.KeystoreB64.IsNull() == data.Path.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("keystore_base64"),
+			"Invalid keystore source",
+			"Exactly one of keystore_base64 or path must be set.",
+		)
+	}
+}
+
+func (d *KeystoreContentsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data KeystoreContentsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// apply provider defaults
+	data.Password = d.defaults.password(data.Password)
+	resp.Diagnostics.Append(requirePassword(data.Password)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// load keystore
+	var (
+		ksData []byte
+		err    error
+	)
+	if !data.Path.IsNull() {
+		if ksData, err = os.ReadFile(data.Path.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("path"),
+				"Error reading keystore file",
+				err.Error(),
+			)
+			return
+		}
+	} else if ksData, err = base64.StdEncoding.DecodeString(data.KeystoreB64.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("keystore_base64"),
+			"Error decoding keystore",
+			err.Error(),
+		)
+		return
+	}
+
+	// decode keystore
+	keyPasswords := make(map[string]string, len(data.KeyPasswords.Elements()))
+	resp.Diagnostics.Append(data.KeyPasswords.ElementsAs(ctx, &keyPasswords, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ks, err := jks.OpenWithKeyPasswords(ksData, data.Password.ValueString(), keyPasswords)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error reading keystore",
+			err.Error(),
+		)
+		return
+	}
+
+	// add keystore contents to model
+	data.StoreType = types.StringValue(string(ks.Type))
+	var diags diag.Diagnostics
+	data.Aliases, diags = types.ListValueFrom(ctx, types.StringType, ks.Aliases())
+	resp.Diagnostics.Append(diags...)
+	data.Entries = make([]KeystoreContentsEntryModel, len(ks.Entries))
+	for i, entry := range ks.Entries {
+		data.Entries[i], err = newKeystoreContentsEntryModel(entry)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error encoding keystore entry",
+				err.Error(),
+			)
+			return
+		}
+	}
+
+	// save model
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// newKeystoreContentsEntryModel converts a keystore entry to its model, with certificates & keys in PEM format.
+func newKeystoreContentsEntryModel(entry *jks.Entry) (KeystoreContentsEntryModel, error) {
+	model := KeystoreContentsEntryModel{
+		Alias:        types.StringValue(entry.Alias),
+		Type:         types.StringValue(string(entry.Type)),
+		Created:      types.StringNull(),
+		PrivateKey:   types.StringNull(),
+		SecretKeyAlg: types.StringNull(),
+		SecretKeyB64: types.StringNull(),
+	}
+
+	if !entry.Created.IsZero() {
+		model.Created = types.StringValue(entry.Created.Format(time.RFC3339))
+	}
+	for _, cert := range entry.CertificateChainPEM() {
+		model.CertificateChain = append(model.CertificateChain, types.StringValue(string(cert)))
+	}
+	key, err := entry.PrivateKeyPEM()
+	if err != nil {
+		return KeystoreContentsEntryModel{}, err
+	}
+	if key != nil {
+		model.PrivateKey = types.StringValue(string(key))
+	}
+	if skEntry, ok := entry.SecretKeyEntry(); ok {
+		model.SecretKeyAlg = types.StringValue(skEntry.Algorithm)
+		model.SecretKeyB64 = types.StringValue(base64.StdEncoding.EncodeToString(skEntry.Key))
+	}
+
+	return model, nil
+}
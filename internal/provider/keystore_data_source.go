@@ -2,11 +2,10 @@ package provider
 
 import (
 	"context"
-	"encoding/base64"
 
-	"github.com/fhke/terraform-provider-jks/jks"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
@@ -15,33 +14,132 @@ func NewKeystoreDataSource() datasource.DataSource {
 }
 
 // KeystoreDataSource defines the data source implementation.
-type KeystoreDataSource struct{}
-
-// KeystoreDataSourceModel describes the data source data model.
-type KeystoreDataSourceModel struct {
-	// Input values
-	KeyPair  types.Set    `tfsdk:"key_pair"`
-	Password types.String `tfsdk:"password"`
-	// Computed values
-	JksB64 types.String `tfsdk:"jks_base64"`
+type KeystoreDataSource struct {
+	// defaults are the provider defaults, or nil if the provider is not configured yet.
+	defaults *JksProviderModel
 }
 
 func (d *KeystoreDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_keystore"
 }
 
+func (d *KeystoreDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	var diags diag.Diagnostics
+	d.defaults, diags = providerDefaults(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+}
+
 func (d *KeystoreDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Description: "Generates a JKS keystore",
+		Description: "Generates a keystore in JKS, PKCS#12, JCEKS or BouncyCastle format",
 
 		Attributes: map[string]schema.Attribute{
 			"password": schema.StringAttribute{
-				Description: "Password for keystore.",
-				Required:    true,
+				Description: "Password for keystore. Defaults to the provider `password`, one of which must be set.",
+				Optional:    true,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"deterministic": schema.BoolAttribute{
+				Description: "Produce identical output for identical inputs, by deriving key protection salts from the inputs & using certificate validity start times as entry timestamps. Defaults to the provider `deterministic`, or `false`.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"build_certificate_chains": schema.BoolAttribute{
+				Description: "Build each key pair's certificate chain automatically, treating `intermediate_certificates`, `certificate_chain` & any further certificates in `certificate` as an unordered pool. Certificates are linked to their issuers by name, key identifier & signature, and certificates that are not part of the chain are dropped. An error is returned if the chain cannot be completed. Defaults to `false`.",
+				Optional:    true,
+			},
+			"min_remaining_validity": schema.StringAttribute{
+				Description: "Minimum remaining validity of key pair certificates, including intermediate certificates, as a duration such as `720h`. A warning is emitted at plan time for each certificate that expires within the duration. Certificates that are expired or not yet valid are always an error. Key pairs from the base keystore are not checked.",
+				Optional:    true,
+			},
+			"store_type": schema.StringAttribute{
+				Description: "Format of the keystore, one of `jks`, `pkcs12`, `jceks`, `bks` (BouncyCastle BKS v2), `bks-v1` or `uber` (BouncyCastle UBER). Defaults to the provider `store_type`, or `jks`.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"base_keystore_base64": schema.StringAttribute{
+				Description: "Base 64 encoded keystore to start from, in any supported format. Its entries are added to the keystore, and are replaced by `key_pair`, `trusted_certificate` & `secret_key` blocks with the same alias, ignoring case. Conflicts with `base_keystore_path`.",
+				Optional:    true,
+			},
+			"base_keystore_path": schema.StringAttribute{
+				Description: "Path to a keystore file to start from, e.g. the JDK `cacerts` file. Behaves like `base_keystore_base64`, with which it conflicts.",
+				Optional:    true,
+			},
+			"base_keystore_password": schema.StringAttribute{
+				Description: "Password for the base keystore & its keys. Defaults to `password`. Keys from the base keystore are protected with `password` in the generated keystore, as `key_password` only applies to `key_pair` & `secret_key` blocks.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"remove_aliases": schema.SetAttribute{
+				Description: "Aliases of base keystore entries to leave out of the keystore, ignoring case. Aliases that are not in the base keystore are ignored.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"keystore_base64": schema.StringAttribute{
+				Description: "Base 64 encoded keystore, in the format set by `store_type`.",
+				Computed:    true,
 			},
 			"jks_base64": schema.StringAttribute{
-				Description: "Base 64 encoded keystore, in JKS format",
+				Description: "Base 64 encoded keystore, in JKS format. Only set when `store_type` is `jks`.",
+				Computed:    true,
+			},
+			"pkcs12_base64": schema.StringAttribute{
+				Description: "Base 64 encoded keystore, in PKCS#12 format. Only set when `store_type` is `pkcs12`.",
+				Computed:    true,
+			},
+			"entries": schema.MapNestedAttribute{
+				Description: "Metadata of each entry's certificate, keyed by alias. For private key entries, this describes the leaf certificate. Secret key entries have no certificate, so only `type`, `key_algorithm` & `key_size` are set.",
 				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"type": schema.StringAttribute{
+							Description: "Type of entry, one of `private_key`, `trusted_certificate` or `secret_key`.",
+							Computed:    true,
+						},
+						"sha1_fingerprint": schema.StringAttribute{
+							Description: "Lowercase hex SHA-1 fingerprint of the certificate.",
+							Computed:    true,
+						},
+						"sha256_fingerprint": schema.StringAttribute{
+							Description: "Lowercase hex SHA-256 fingerprint of the certificate.",
+							Computed:    true,
+						},
+						"subject": schema.StringAttribute{
+							Description: "Subject distinguished name of the certificate.",
+							Computed:    true,
+						},
+						"issuer": schema.StringAttribute{
+							Description: "Issuer distinguished name of the certificate.",
+							Computed:    true,
+						},
+						"serial_number": schema.StringAttribute{
+							Description: "Lowercase hex serial number of the certificate.",
+							Computed:    true,
+						},
+						"not_before": schema.StringAttribute{
+							Description: "Start of the certificate validity period, in RFC 3339 format.",
+							Computed:    true,
+						},
+						"not_after": schema.StringAttribute{
+							Description: "End of the certificate validity period, in RFC 3339 format.",
+							Computed:    true,
+						},
+						"subject_alternative_names": schema.ListAttribute{
+							Description: "Subject alternative names of the certificate, prefixed with their type, e.g. `DNS:example.com` or `IP Address:127.0.0.1`.",
+							ElementType: types.StringType,
+							Computed:    true,
+						},
+						"key_algorithm": schema.StringAttribute{
+							Description: "Public key algorithm of the certificate, e.g. `RSA`, `EC` or `Ed25519`, or the algorithm of a secret key, e.g. `AES`.",
+							Computed:    true,
+						},
+						"key_size": schema.Int64Attribute{
+							Description: "Public or secret key size in bits, or `0` if the key algorithm is unsupported.",
+							Computed:    true,
+						},
+					},
+				},
 			},
 		},
 		Blocks: map[string]schema.Block{
@@ -51,20 +149,82 @@ func (d *KeystoreDataSource) Schema(ctx context.Context, req datasource.SchemaRe
 					Attributes: map[string]schema.Attribute{
 						"alias": schema.StringAttribute{
 							Required:    true,
-							Description: "Alias for key pair. Must be unique within keystore.",
+							Description: "Alias for key pair. Must be unique within keystore, ignoring case, and at most 255 characters without control characters or leading & trailing whitespace.",
 						},
 						"certificate": schema.StringAttribute{
 							Required:    true,
-							Description: "Certificate in PEM format.",
+							Description: "Certificate in PEM format. Any further certificates, e.g. in a full chain file from an ACME client, are added to the certificate chain before `intermediate_certificates`.",
 						},
 						"private_key": schema.StringAttribute{
 							Required:    true,
-							Description: "Private key for certificate in PEM format.",
+							Description: "Private key for certificate in PEM format. RSA, ECDSA (P-256, P-384 or P-521) & Ed25519 keys are supported, in `PRIVATE KEY`, `ENCRYPTED PRIVATE KEY`, `RSA PRIVATE KEY` or `EC PRIVATE KEY` blocks. Other key blocks, such as `OPENSSH PRIVATE KEY`, are rejected. Other PEM blocks, such as `EC PARAMETERS`, are ignored, but only one private key may be given.",
+						},
+						"private_key_password": schema.StringAttribute{
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Password for an encrypted private key, either encrypted PKCS#8 (`ENCRYPTED PRIVATE KEY`) or legacy OpenSSL encryption (`Proc-Type: 4,ENCRYPTED`).",
+						},
+						"key_password": schema.StringAttribute{
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Password protecting the private key in the keystore, e.g. for a separate `keyPassword` & `keystorePassword`. Defaults to the provider `key_password`, or the keystore password.",
+						},
+						"trusted_roots": schema.StringAttribute{
+							Optional:    true,
+							Description: "Bundle of trusted root certificates in PEM format. When set, the certificate chain is verified against them, checking signatures, CA basic constraints, path lengths & validity periods, and that the chain is in issuer order.",
+						},
+						"strict_chain_validation": schema.BoolAttribute{
+							Optional:    true,
+							Description: "Whether a certificate chain that fails verification against `trusted_roots` is an error, rather than a warning. Defaults to the provider `strict_chain_validation`, or `true`.",
 						},
 						"intermediate_certificates": schema.ListAttribute{
 							ElementType: types.StringType,
 							Optional:    true,
-							Description: "List of intermediate certificate authority certificates in PEM format. Root certificates should not be added here.",
+							Description: "List of intermediate certificate authority certificates in PEM format, in chain order unless `build_certificate_chains` is set. Root certificates should not be added here.",
+						},
+						"certificate_chain": schema.StringAttribute{
+							Optional:    true,
+							Description: "Bundle of intermediate certificate authority certificates in PEM format, as an alternative to `intermediate_certificates`, after which they are added to the chain. In chain order unless `build_certificate_chains` is set. Root certificates should not be added here.",
+						},
+					},
+				},
+			},
+			"trusted_certificate": schema.SetNestedBlock{
+				Description: "Block defining a trusted certificate, e.g. a certificate authority for a truststore.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"alias": schema.StringAttribute{
+							Required:    true,
+							Description: "Alias for trusted certificate. Must be unique within keystore, ignoring case, and at most 255 characters without control characters or leading & trailing whitespace.",
+						},
+						"certificate": schema.StringAttribute{
+							Required:    true,
+							Description: "Certificate in PEM format.",
+						},
+					},
+				},
+			},
+			"secret_key": schema.SetNestedBlock{
+				Description: "Block defining a secret key, e.g. an AES or HMAC key as generated by `keytool -genseckey`. Secret keys are not supported by the `jks` store type.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"alias": schema.StringAttribute{
+							Required:    true,
+							Description: "Alias for secret key. Must be unique within keystore, ignoring case, and at most 255 characters without control characters or leading & trailing whitespace.",
+						},
+						"algorithm": schema.StringAttribute{
+							Required:    true,
+							Description: "Java algorithm name of the secret key, one of `AES`, `DESede`, `HmacSHA1`, `HmacSHA224`, `HmacSHA256`, `HmacSHA384` or `HmacSHA512`.",
+						},
+						"key_base64": schema.StringAttribute{
+							Required:    true,
+							Sensitive:   true,
+							Description: "Base 64 encoded key material. AES keys must be 16, 24 or 32 bytes, and DESede keys 24 bytes.",
+						},
+						"key_password": schema.StringAttribute{
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Password protecting the secret key in the keystore. Defaults to the provider `key_password`, or the keystore password.",
 						},
 					},
 				},
@@ -73,50 +233,39 @@ func (d *KeystoreDataSource) Schema(ctx context.Context, req datasource.SchemaRe
 	}
 }
 
-func (d *KeystoreDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
-	var data KeystoreDataSourceModel
+func (d *KeystoreDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var data KeystoreModel
 
 	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// create jks builder
-	bld := jks.NewKeystoreBuilder()
-
-	// set store password
-	bld.SetPassword(data.Password.ValueString())
-
-	for _, kpElem := range data.KeyPair.Elements() {
-		keyPair := kpElem.(types.Object).Attributes()
+	resp.Diagnostics.Append(data.validateAliases()...)
+	resp.Diagnostics.Append(data.validateMinValidity()...)
+	resp.Diagnostics.Append(data.validateKeyPairs(d.defaults)...)
+	resp.Diagnostics.Append(data.validateSecretKeys(d.defaults)...)
+}
 
-		// get intermediate certs as [][]byte
-		caCerts := make([][]byte, 0)
-		for _, crtElem := range keyPair["intermediate_certificates"].(types.List).Elements() {
-			caCerts = append(caCerts, []byte(crtElem.(types.String).ValueString()))
-		}
+func (d *KeystoreDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data KeystoreModel
 
-		// Add cert to store
-		bld.AddCert(
-			keyPair["alias"].(types.String).ValueString(),
-			[]byte(keyPair["certificate"].(types.String).ValueString()),
-			[]byte(keyPair["private_key"].(types.String).ValueString()),
-			caCerts...,
-		)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	// build jks keystore
-	jksData, err := bld.Build()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error creating JKS keystore",
-			err.Error(),
-		)
+	// apply provider defaults
+	resp.Diagnostics.Append(data.applyDefaults(d.defaults)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// base64 encode jks & add to model
-	data.JksB64 = types.StringValue(base64.StdEncoding.EncodeToString(jksData))
+	// build keystore
+	resp.Diagnostics.Append(data.build(ctx, d.defaults)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// save model
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -0,0 +1,320 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func NewKeystoreFileResource() resource.Resource {
+	return &KeystoreFileResource{}
+}
+
+// KeystoreFileResource defines the resource implementation.
+// The keystore is generated on create & written to a file, which is recreated when it's changed or deleted.
+type KeystoreFileResource struct {
+	// defaults are the provider defaults, or nil if the provider is not configured yet.
+	defaults *JksProviderModel
+}
+
+// KeystoreFileModel describes the resource data model.
+// The keystore inputs & entries are shared with the keystore resource, the file values extend them.
+type KeystoreFileModel struct {
+	// Keystore values
+	Keystore KeystoreModel `tfsdk:"-"`
+	// File values
+	Filename            types.String `tfsdk:"filename"`
+	FilePermission      types.String `tfsdk:"file_permission"`
+	DirectoryPermission types.String `tfsdk:"directory_permission"`
+	CreateDirectories   types.Bool   `tfsdk:"create_directories"`
+	// Computed values
+	ContentSHA256 types.String `tfsdk:"content_sha256"`
+}
+
+// get reads the keystore & file values of the model from src.
+func (m *KeystoreFileModel) get(ctx context.Context, src attributeGetter, sch attributeTypes) diag.Diagnostics {
+	diags := getAttributes(ctx, src, sch, &m.Keystore)
+	diags.Append(getAttributes(ctx, src, sch, m)...)
+	return diags
+}
+
+// set writes the keystore & file values of the model to state.
+func (m *KeystoreFileModel) set(ctx context.Context, state *tfsdk.State) diag.Diagnostics {
+	diags := setAttributes(ctx, state, &m.Keystore)
+	diags.Append(setAttributes(ctx, state, m)...)
+	return diags
+}
+
+func (r *KeystoreFileResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_keystore_file"
+}
+
+func (r *KeystoreFileResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	var diags diag.Diagnostics
+	r.defaults, diags = providerDefaults(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *KeystoreFileResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	attributes := keystoreResourceAttributes()
+	attributes["filename"] = schema.StringAttribute{
+		Description: "Path of the keystore file.",
+		Required:    true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["file_permission"] = schema.StringAttribute{
+		Description: "Permissions of the keystore file, as an octal string such as `0600`. Defaults to `0600`.",
+		Optional:    true,
+		Computed:    true,
+		Default:     stringdefault.StaticString("0600"),
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["directory_permission"] = schema.StringAttribute{
+		Description: "Permissions of directories created for the keystore file, as an octal string such as `0700`. Defaults to `0700`.",
+		Optional:    true,
+		Computed:    true,
+		Default:     stringdefault.StaticString("0700"),
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["create_directories"] = schema.BoolAttribute{
+		Description: "Create missing parent directories of the keystore file. Defaults to `true`.",
+		Optional:    true,
+		Computed:    true,
+		Default:     booldefault.StaticBool(true),
+		PlanModifiers: []planmodifier.Bool{
+			boolplanmodifier.RequiresReplace(),
+		},
+	}
+	attributes["content_sha256"] = schema.StringAttribute{
+		Description: "Hex SHA-256 checksum of the keystore file. The file is recreated when its checksum no longer matches, or when it's deleted.",
+		Computed:    true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.UseStateForUnknown(),
+		},
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "Generates a keystore in JKS, PKCS#12, JCEKS or BouncyCastle format & writes it to a file. The keystore is only regenerated when an input changes, or when the file is changed or deleted.",
+		Attributes:  attributes,
+		Blocks:      keystoreResourceBlocks(),
+	}
+}
+
+func (r *KeystoreFileResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data KeystoreFileModel
+
+	resp.Diagnostics.Append(data.get(ctx, req.Config, req.Config.Schema)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validatePermission(path.Root("file_permission"), data.FilePermission)...)
+	resp.Diagnostics.Append(validatePermission(path.Root("directory_permission"), data.DirectoryPermission)...)
+	resp.Diagnostics.Append(data.Keystore.validateAliases()...)
+	resp.Diagnostics.Append(data.Keystore.validateMinValidity()...)
+	resp.Diagnostics.Append(data.Keystore.validateKeyPairs(r.defaults)...)
+	resp.Diagnostics.Append(data.Keystore.validateSecretKeys(r.defaults)...)
+}
+
+// ModifyPlan applies the provider defaults to the planned keystore.
+func (r *KeystoreFileResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to plan when destroying
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data KeystoreFileModel
+
+	resp.Diagnostics.Append(data.get(ctx, req.Config, req.Config.Schema)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planDefaults(ctx, &data.Keystore, r.defaults, req, resp)
+}
+
+func (r *KeystoreFileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data KeystoreFileModel
+
+	resp.Diagnostics.Append(data.get(ctx, req.Plan, req.Plan.Schema)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// build keystore
+	resp.Diagnostics.Append(data.Keystore.build(ctx, r.defaults)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ksData, err := base64.StdEncoding.DecodeString(data.Keystore.KeystoreB64.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error decoding keystore",
+			err.Error(),
+		)
+		return
+	}
+	// write keystore file
+	resp.Diagnostics.Append(data.writeFile(ksData)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ContentSHA256 = types.StringValue(sha256Hex(ksData))
+
+	// save model
+	resp.Diagnostics.Append(data.set(ctx, &resp.State)...)
+}
+
+// Read removes the resource from state if the keystore file was changed or deleted, so it's recreated.
+func (r *KeystoreFileResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data KeystoreFileModel
+
+	resp.Diagnostics.Append(data.get(ctx, req.State, req.State.Schema)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ksData, err := os.ReadFile(data.Filename.ValueString())
+	if errors.Is(err, os.ErrNotExist) {
+		resp.State.RemoveResource(ctx)
+		return
+	} else if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("filename"),
+			"Error reading keystore file",
+			err.Error(),
+		)
+		return
+	}
+	if sha256Hex(ksData) != data.ContentSHA256.ValueString() {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(data.set(ctx, &resp.State)...)
+}
+
+// Update persists the plan with the keystore file details from state, as every input that changes the keystore file
+// requires replacement.
+func (r *KeystoreFileResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, prior KeystoreFileModel
+
+	resp.Diagnostics.Append(data.get(ctx, req.Plan, req.Plan.Schema)...)
+	resp.Diagnostics.Append(prior.get(ctx, req.State, req.State.Schema)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Keystore.keepComputed(&prior.Keystore)
+	data.ContentSHA256 = prior.ContentSHA256
+
+	resp.Diagnostics.Append(data.set(ctx, &resp.State)...)
+}
+
+// Delete removes the keystore file, ignoring files that were already deleted.
+func (r *KeystoreFileResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data KeystoreFileModel
+
+	resp.Diagnostics.Append(data.get(ctx, req.State, req.State.Schema)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := os.Remove(data.Filename.ValueString()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("filename"),
+			"Error deleting keystore file",
+			err.Error(),
+		)
+	}
+}
+
+// writeFile writes the keystore to the file described by the model, creating parent directories if enabled.
+func (m *KeystoreFileModel) writeFile(ksData []byte) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	filename := m.Filename.ValueString()
+	filePerm, err := parsePermission(m.FilePermission.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("file_permission"), "Invalid file permission", err.Error())
+		return diags
+	}
+
+	if m.CreateDirectories.ValueBool() {
+		dirPerm, err := parsePermission(m.DirectoryPermission.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("directory_permission"), "Invalid directory permission", err.Error())
+			return diags
+		}
+		if err := os.MkdirAll(filepath.Dir(filename), dirPerm); err != nil {
+			diags.AddAttributeError(path.Root("filename"), "Error creating keystore directory", err.Error())
+			return diags
+		}
+	}
+
+	if err := os.WriteFile(filename, ksData, filePerm); err != nil {
+		diags.AddAttributeError(path.Root("filename"), "Error writing keystore file", err.Error())
+		return diags
+	}
+	// WriteFile only applies permissions to new files & is subject to the umask
+	if err := os.Chmod(filename, filePerm); err != nil {
+		diags.AddAttributeError(path.Root("filename"), "Error setting keystore file permissions", err.Error())
+	}
+
+	return diags
+}
+
+// validatePermission checks that a known permission is a valid octal string.
+func validatePermission(attrPath path.Path, perm types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if perm.IsNull() || perm.IsUnknown() {
+		return diags
+	}
+	if _, err := parsePermission(perm.ValueString()); err != nil {
+		diags.AddAttributeError(attrPath, "Invalid permission", err.Error())
+	}
+
+	return diags
+}
+
+// parsePermission parses an octal permission string such as 0600.
+func parsePermission(perm string) (os.FileMode, error) {
+	if len(perm) < 3 || len(perm) > 4 {
+		return 0, fmt.Errorf("permission %q must be 3 or 4 octal digits", perm)
+	}
+	mode, err := strconv.ParseUint(perm, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("permission %q must be an octal string between 0000 & 0777", perm)
+	}
+	return os.FileMode(mode), nil
+}
+
+// sha256Hex returns the hex SHA-256 checksum of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fhke/terraform-provider-jks/test/util"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the keystore file is written on create, and the resource is removed from state when the file drifts.
+func TestKeystoreFileResource(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		modify  func(t *testing.T, filename string)
+		removed bool
+	}{
+		{
+			name:   "Unchanged file",
+			modify: func(t *testing.T, filename string) {},
+		},
+		{
+			name: "Changed file",
+			modify: func(t *testing.T, filename string) {
+				require.NoError(t, os.WriteFile(filename, []byte("changed"), 0600), "It should change keystore file")
+			},
+			removed: true,
+		},
+		{
+			name: "Deleted file",
+			modify: func(t *testing.T, filename string) {
+				require.NoError(t, os.Remove(filename), "It should delete keystore file")
+			},
+			removed: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.TODO()
+			filename := filepath.Join(t.TempDir(), "certs", "truststore.jks")
+			_, crt := util.NewSelfSignedCertPEM(t)
+
+			r := NewKeystoreFileResource()
+			var schemaResp resource.SchemaResponse
+			r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+			require.False(t, schemaResp.Diagnostics.HasError(), "It should return schema")
+			sch := schemaResp.Schema
+
+			// plan keystore with one trusted certificate
+			plan := tfsdk.Plan{
+				Schema: sch,
+				Raw:    tftypes.NewValue(sch.Type().TerraformType(ctx), nil),
+			}
+			entriesType, diags := sch.TypeAtPath(ctx, path.Root("entries"))
+			require.False(t, diags.HasError(), "It should return entries type")
+			for name, value := range map[string]attr.Value{
+				"trusted_certificate":  trustedCertificates(ctx, t, sch, "ca", crt),
+				"password":             types.StringValue("changeit"),
+				"store_type":           types.StringValue("jks"),
+				"filename":             types.StringValue(filename),
+				"file_permission":      types.StringValue("0640"),
+				"directory_permission": types.StringValue("0700"),
+				"create_directories":   types.BoolValue(true),
+				"content_sha256":       types.StringUnknown(),
+				"entries":              attrValue(ctx, t, entriesType, tftypes.UnknownValue),
+			} {
+				require.False(t, plan.SetAttribute(ctx, path.Root(name), value).HasError(), "It should plan %s", name)
+			}
+
+			// create
+			createResp := resource.CreateResponse{State: tfsdk.State{
+				Schema: sch,
+				Raw:    tftypes.NewValue(sch.Type().TerraformType(ctx), nil),
+			}}
+			r.Create(ctx, resource.CreateRequest{Plan: plan}, &createResp)
+			require.False(t, createResp.Diagnostics.HasError(), "It should create keystore file: %v", createResp.Diagnostics)
+
+			ksData, err := os.ReadFile(filename)
+			require.NoError(t, err, "It should write keystore file")
+			info, err := os.Stat(filename)
+			require.NoError(t, err, "It should stat keystore file")
+			assert.Equal(t, os.FileMode(0640), info.Mode().Perm(), "It should set file permissions")
+
+			var created KeystoreFileModel
+			require.False(t, created.get(ctx, createResp.State, sch).HasError(), "It should read state")
+			assert.Equal(t, sha256Hex(ksData), created.ContentSHA256.ValueString(), "It should save checksum")
+			assert.Equal(t, filename, created.Filename.ValueString(), "It should save filename")
+			assert.Equal(t, "jks", created.Keystore.StoreType.ValueString(), "It should save store type")
+			assert.Len(t, created.Keystore.Entries.Elements(), 1, "It should save entries")
+
+			// read after modifying the file
+			tc.modify(t, filename)
+			readResp := resource.ReadResponse{State: createResp.State}
+			r.Read(ctx, resource.ReadRequest{State: createResp.State}, &readResp)
+			require.False(t, readResp.Diagnostics.HasError(), "It should read keystore file: %v", readResp.Diagnostics)
+			assert.Equal(t, tc.removed, readResp.State.Raw.IsNull(), "It should only remove resource if file changed")
+			if !tc.removed {
+				assert.True(t, readResp.State.Raw.Equal(createResp.State.Raw), "It should keep state")
+			}
+		})
+	}
+}
+
+// attrValue returns a value of attrType from a raw Terraform value, e.g. nil for null.
+func attrValue(ctx context.Context, t *testing.T, attrType attr.Type, raw any) attr.Value {
+	value, err := attrType.ValueFromTerraform(ctx, tftypes.NewValue(attrType.TerraformType(ctx), raw))
+	require.NoError(t, err, "It should create value")
+	return value
+}
@@ -0,0 +1,505 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func NewKeystoreResource() resource.Resource {
+	return &KeystoreResource{}
+}
+
+// KeystoreResource defines the resource implementation.
+// The keystore is generated on create & kept in state, any change to the inputs replaces the resource.
+type KeystoreResource struct {
+	// defaults are the provider defaults, or nil if the provider is not configured yet.
+	defaults *JksProviderModel
+}
+
+func (r *KeystoreResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_keystore"
+}
+
+func (r *KeystoreResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	var diags diag.Diagnostics
+	r.defaults, diags = providerDefaults(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *KeystoreResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	attributes := keystoreResourceAttributes()
+	attributes["keystore_base64"] = schema.StringAttribute{
+		Description: "Base 64 encoded keystore, in the format set by `store_type`.",
+		Computed:    true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.UseStateForUnknown(),
+		},
+	}
+	attributes["jks_base64"] = schema.StringAttribute{
+		Description: "Base 64 encoded keystore, in JKS format. Only set when `store_type` is `jks`.",
+		Computed:    true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.UseStateForUnknown(),
+		},
+	}
+	attributes["pkcs12_base64"] = schema.StringAttribute{
+		Description: "Base 64 encoded keystore, in PKCS#12 format. Only set when `store_type` is `pkcs12`.",
+		Computed:    true,
+		PlanModifiers: []planmodifier.String{
+			stringplanmodifier.UseStateForUnknown(),
+		},
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "Generates a keystore in JKS, PKCS#12, JCEKS or BouncyCastle format & stores it in state. The keystore is only regenerated when an input changes.",
+		Attributes:  attributes,
+		Blocks:      keystoreResourceBlocks(),
+	}
+}
+
+// keystoreResourceAttributes returns the attributes shared by the keystore resources.
+func keystoreResourceAttributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"password": schema.StringAttribute{
+			Description: "Password for keystore. Defaults to the provider `password`, one of which must be set.",
+			Optional:    true,
+			Computed:    true,
+			Sensitive:   true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplace(),
+			},
+		},
+		"deterministic": schema.BoolAttribute{
+			Description: "Produce identical output for identical inputs, by deriving key protection salts from the inputs & using certificate validity start times as entry timestamps. Defaults to the provider `deterministic`, or `false`.",
+			Optional:    true,
+			Computed:    true,
+			PlanModifiers: []planmodifier.Bool{
+				boolplanmodifier.RequiresReplace(),
+			},
+		},
+		"build_certificate_chains": schema.BoolAttribute{
+			Description: "Build each key pair's certificate chain automatically, treating `intermediate_certificates`, `certificate_chain` & any further certificates in `certificate` as an unordered pool. Certificates are linked to their issuers by name, key identifier & signature, and certificates that are not part of the chain are dropped. An error is returned if the chain cannot be completed. Defaults to `false`.",
+			Optional:    true,
+			PlanModifiers: []planmodifier.Bool{
+				boolplanmodifier.RequiresReplace(),
+			},
+		},
+		"min_remaining_validity": schema.StringAttribute{
+			Description: "Minimum remaining validity of key pair certificates, including intermediate certificates, as a duration such as `720h`. A warning is emitted at plan time for each certificate that expires within the duration. Certificates that are expired or not yet valid are always an error. Key pairs from the base keystore are not checked. Changing it doesn't replace the keystore.",
+			Optional:    true,
+		},
+		"store_type": schema.StringAttribute{
+			Description: "Format of the keystore, one of `jks`, `pkcs12`, `jceks`, `bks` (BouncyCastle BKS v2), `bks-v1` or `uber` (BouncyCastle UBER). Defaults to the provider `store_type`, or `jks`.",
+			Optional:    true,
+			Computed:    true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplace(),
+			},
+		},
+		"base_keystore_base64": schema.StringAttribute{
+			Description: "Base 64 encoded keystore to start from, in any supported format. Its entries are added to the keystore, and are replaced by `key_pair`, `trusted_certificate` & `secret_key` blocks with the same alias, ignoring case. Conflicts with `base_keystore_path`.",
+			Optional:    true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplace(),
+			},
+		},
+		"base_keystore_path": schema.StringAttribute{
+			Description: "Path to a keystore file to start from, e.g. the JDK `cacerts` file. The file is only read when the keystore is generated, so changes to its contents are not detected. Behaves like `base_keystore_base64`, with which it conflicts.",
+			Optional:    true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplace(),
+			},
+		},
+		"base_keystore_password": schema.StringAttribute{
+			Description: "Password for the base keystore & its keys. Defaults to `password`. Keys from the base keystore are protected with `password` in the generated keystore, as `key_password` only applies to `key_pair` & `secret_key` blocks.",
+			Optional:    true,
+			Sensitive:   true,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplace(),
+			},
+		},
+		"remove_aliases": schema.SetAttribute{
+			Description: "Aliases of base keystore entries to leave out of the keystore, ignoring case. Aliases that are not in the base keystore are ignored.",
+			ElementType: types.StringType,
+			Optional:    true,
+			PlanModifiers: []planmodifier.Set{
+				setplanmodifier.RequiresReplace(),
+			},
+		},
+		"entries": schema.MapNestedAttribute{
+			Description: "Metadata of each entry's certificate, keyed by alias. For private key entries, this describes the leaf certificate. Secret key entries have no certificate, so only `type`, `key_algorithm` & `key_size` are set.",
+			Computed:    true,
+			PlanModifiers: []planmodifier.Map{
+				mapplanmodifier.UseStateForUnknown(),
+			},
+			NestedObject: schema.NestedAttributeObject{
+				Attributes: map[string]schema.Attribute{
+					"type": schema.StringAttribute{
+						Description: "Type of entry, one of `private_key`, `trusted_certificate` or `secret_key`.",
+						Computed:    true,
+					},
+					"sha1_fingerprint": schema.StringAttribute{
+						Description: "Lowercase hex SHA-1 fingerprint of the certificate.",
+						Computed:    true,
+					},
+					"sha256_fingerprint": schema.StringAttribute{
+						Description: "Lowercase hex SHA-256 fingerprint of the certificate.",
+						Computed:    true,
+					},
+					"subject": schema.StringAttribute{
+						Description: "Subject distinguished name of the certificate.",
+						Computed:    true,
+					},
+					"issuer": schema.StringAttribute{
+						Description: "Issuer distinguished name of the certificate.",
+						Computed:    true,
+					},
+					"serial_number": schema.StringAttribute{
+						Description: "Lowercase hex serial number of the certificate.",
+						Computed:    true,
+					},
+					"not_before": schema.StringAttribute{
+						Description: "Start of the certificate validity period, in RFC 3339 format.",
+						Computed:    true,
+					},
+					"not_after": schema.StringAttribute{
+						Description: "End of the certificate validity period, in RFC 3339 format.",
+						Computed:    true,
+					},
+					"subject_alternative_names": schema.ListAttribute{
+						Description: "Subject alternative names of the certificate, prefixed with their type, e.g. `DNS:example.com` or `IP Address:127.0.0.1`.",
+						ElementType: types.StringType,
+						Computed:    true,
+					},
+					"key_algorithm": schema.StringAttribute{
+						Description: "Public key algorithm of the certificate, e.g. `RSA`, `EC` or `Ed25519`, or the algorithm of a secret key, e.g. `AES`.",
+						Computed:    true,
+					},
+					"key_size": schema.Int64Attribute{
+						Description: "Public or secret key size in bits, or `0` if the key algorithm is unsupported.",
+						Computed:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// keystoreResourceBlocks returns the blocks shared by the keystore resources.
+func keystoreResourceBlocks() map[string]schema.Block {
+	return map[string]schema.Block{
+		"key_pair": schema.SetNestedBlock{
+			Description: "Block defining a cert & key pair.",
+			PlanModifiers: []planmodifier.Set{
+				keyPairRequiresReplace(),
+			},
+			NestedObject: schema.NestedBlockObject{
+				Attributes: map[string]schema.Attribute{
+					"alias": schema.StringAttribute{
+						Required:    true,
+						Description: "Alias for key pair. Must be unique within keystore, ignoring case, and at most 255 characters without control characters or leading & trailing whitespace.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "Certificate in PEM format. Any further certificates, e.g. in a full chain file from an ACME client, are added to the certificate chain before `intermediate_certificates`.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"private_key": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "Private key for certificate in PEM format. RSA, ECDSA (P-256, P-384 or P-521) & Ed25519 keys are supported, in `PRIVATE KEY`, `ENCRYPTED PRIVATE KEY`, `RSA PRIVATE KEY` or `EC PRIVATE KEY` blocks. Other key blocks, such as `OPENSSH PRIVATE KEY`, are rejected. Other PEM blocks, such as `EC PARAMETERS`, are ignored, but only one private key may be given.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"private_key_password": schema.StringAttribute{
+						Optional:    true,
+						Sensitive:   true,
+						Description: "Password for an encrypted private key, either encrypted PKCS#8 (`ENCRYPTED PRIVATE KEY`) or legacy OpenSSL encryption (`Proc-Type: 4,ENCRYPTED`).",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"key_password": schema.StringAttribute{
+						Optional:    true,
+						Sensitive:   true,
+						Description: "Password protecting the private key in the keystore, e.g. for a separate `keyPassword` & `keystorePassword`. Defaults to the provider `key_password`, or the keystore password.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"trusted_roots": schema.StringAttribute{
+						Optional:    true,
+						Description: "Bundle of trusted root certificates in PEM format. When set, the certificate chain is verified against them, checking signatures, CA basic constraints, path lengths & validity periods, and that the chain is in issuer order. Changing it doesn't replace the keystore.",
+					},
+					"strict_chain_validation": schema.BoolAttribute{
+						Optional:    true,
+						Description: "Whether a certificate chain that fails verification against `trusted_roots` is an error, rather than a warning. Defaults to the provider `strict_chain_validation`, or `true`. Changing it doesn't replace the keystore.",
+					},
+					"intermediate_certificates": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "List of intermediate certificate authority certificates in PEM format, in chain order unless `build_certificate_chains` is set. Root certificates should not be added here.",
+						PlanModifiers: []planmodifier.List{
+							listplanmodifier.RequiresReplace(),
+						},
+					},
+					"certificate_chain": schema.StringAttribute{
+						Optional:    true,
+						Description: "Bundle of intermediate certificate authority certificates in PEM format, as an alternative to `intermediate_certificates`, after which they are added to the chain. In chain order unless `build_certificate_chains` is set. Root certificates should not be added here.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+				},
+			},
+		},
+		"trusted_certificate": schema.SetNestedBlock{
+			Description: "Block defining a trusted certificate, e.g. a certificate authority for a truststore.",
+			PlanModifiers: []planmodifier.Set{
+				setplanmodifier.RequiresReplace(),
+			},
+			NestedObject: schema.NestedBlockObject{
+				Attributes: map[string]schema.Attribute{
+					"alias": schema.StringAttribute{
+						Required:    true,
+						Description: "Alias for trusted certificate. Must be unique within keystore, ignoring case, and at most 255 characters without control characters or leading & trailing whitespace.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"certificate": schema.StringAttribute{
+						Required:    true,
+						Description: "Certificate in PEM format.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+				},
+			},
+		},
+		"secret_key": schema.SetNestedBlock{
+			Description: "Block defining a secret key, e.g. an AES or HMAC key as generated by `keytool -genseckey`. Secret keys are not supported by the `jks` store type.",
+			PlanModifiers: []planmodifier.Set{
+				setplanmodifier.RequiresReplace(),
+			},
+			NestedObject: schema.NestedBlockObject{
+				Attributes: map[string]schema.Attribute{
+					"alias": schema.StringAttribute{
+						Required:    true,
+						Description: "Alias for secret key. Must be unique within keystore, ignoring case, and at most 255 characters without control characters or leading & trailing whitespace.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"algorithm": schema.StringAttribute{
+						Required:    true,
+						Description: "Java algorithm name of the secret key, one of `AES`, `DESede`, `HmacSHA1`, `HmacSHA224`, `HmacSHA256`, `HmacSHA384` or `HmacSHA512`.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"key_base64": schema.StringAttribute{
+						Required:    true,
+						Sensitive:   true,
+						Description: "Base 64 encoded key material. AES keys must be 16, 24 or 32 bytes, and DESede keys 24 bytes.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"key_password": schema.StringAttribute{
+						Optional:    true,
+						Sensitive:   true,
+						Description: "Password protecting the secret key in the keystore. Defaults to the provider `key_password`, or the keystore password.",
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *KeystoreResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data KeystoreModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(data.validateAliases()...)
+	resp.Diagnostics.Append(data.validateMinValidity()...)
+	resp.Diagnostics.Append(data.validateKeyPairs(r.defaults)...)
+	resp.Diagnostics.Append(data.validateSecretKeys(r.defaults)...)
+}
+
+// ModifyPlan applies the provider defaults to the planned keystore.
+func (r *KeystoreResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// nothing to plan when destroying
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data KeystoreModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planDefaults(ctx, &data, r.defaults, req, resp)
+}
+
+func (r *KeystoreResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data KeystoreModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// build keystore
+	resp.Diagnostics.Append(data.build(ctx, r.defaults)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// save model
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Read is a no-op, the keystore only exists in state.
+func (r *KeystoreResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data KeystoreModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Update persists the plan with the keystore from state, as every input that changes the keystore requires
+// replacement.
+func (r *KeystoreResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, prior KeystoreModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.keepComputed(&prior)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete is a no-op, the keystore is removed from state by the framework.
+func (r *KeystoreResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
+
+// planDefaults sets the password, store type & deterministic attributes of a planned keystore resource to their
+// configured values or the provider defaults. As the defaults aren't part of the resource configuration, the
+// resource is replaced explicitly when a default changes.
+func planDefaults(ctx context.Context, config *KeystoreModel, defaults *JksProviderModel, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	resp.Diagnostics.Append(config.applyDefaults(defaults)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, planned := range []struct {
+		name  string
+		value attr.Value
+	}{
+		{"password", config.Password},
+		{"store_type", config.StoreType},
+		{"deterministic", config.Deterministic},
+	} {
+		attrPath := path.Root(planned.name)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, attrPath, planned.value)...)
+
+		// replace existing keystores if the value changed
+		if req.State.Raw.IsNull() {
+			continue
+		}
+		var prior attr.Value
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, attrPath, &prior)...)
+		if prior != nil && !prior.Equal(planned.value) {
+			resp.RequiresReplace = resp.RequiresReplace.Append(attrPath)
+		}
+	}
+}
+
+// keyPairValidationAttributes are the key_pair attributes that only control validation, so don't change the keystore.
+var keyPairValidationAttributes = map[string]attr.Value{
+	"trusted_roots":           types.StringNull(),
+	"strict_chain_validation": types.BoolNull(),
+}
+
+// keyPairRequiresReplace replaces the keystore when the key pairs change, ignoring changes to attributes that only
+// control validation.
+func keyPairRequiresReplace() planmodifier.Set {
+	return setplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.SetRequest, resp *setplanmodifier.RequiresReplaceIfFuncResponse) {
+			planned, diags := withoutValidationAttributes(ctx, req.PlanValue)
+			resp.Diagnostics.Append(diags...)
+			prior, diags := withoutValidationAttributes(ctx, req.StateValue)
+			resp.Diagnostics.Append(diags...)
+			resp.RequiresReplace = !planned.Equal(prior)
+		},
+		"Changing a key pair requires replacement, unless only trusted_roots or strict_chain_validation change.",
+		"Changing a key pair requires replacement, unless only `trusted_roots` or `strict_chain_validation` change.",
+	)
+}
+
+// withoutValidationAttributes returns the key pairs with the attributes that only control validation set to null.
+func withoutValidationAttributes(ctx context.Context, keyPairs types.Set) (types.Set, diag.Diagnostics) {
+	if keyPairs.IsNull() || keyPairs.IsUnknown() {
+		return keyPairs, nil
+	}
+
+	var diags diag.Diagnostics
+	elems := make([]attr.Value, 0, len(keyPairs.Elements()))
+	for _, elem := range keyPairs.Elements() {
+		keyPair, ok := elem.(types.Object)
+		if !ok || keyPair.IsNull() || keyPair.IsUnknown() {
+			elems = append(elems, elem)
+			continue
+		}
+
+		attrs := make(map[string]attr.Value, len(keyPair.Attributes()))
+		for name, value := range keyPair.Attributes() {
+			if null, ok := keyPairValidationAttributes[name]; ok {
+				value = null
+			}
+			attrs[name] = value
+		}
+		stripped, objDiags := types.ObjectValue(keyPair.AttributeTypes(ctx), attrs)
+		diags.Append(objDiags...)
+		elems = append(elems, stripped)
+	}
+
+	stripped, setDiags := types.SetValue(keyPairs.ElementType(ctx), elems)
+	diags.Append(setDiags...)
+	return stripped, diags
+}
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/fhke/terraform-provider-jks/test/util"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the keystore is generated on create & kept in state, and only replaced when an input that changes it
+// changes.
+func TestKeystoreResource(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  map[string]attr.Value
+		replace path.Paths
+	}{
+		{
+			name: "Unchanged",
+		},
+		{
+			name:   "Changed minimum remaining validity",
+			config: map[string]attr.Value{"min_remaining_validity": types.StringValue("1h")},
+		},
+		{
+			name:    "Changed password",
+			config:  map[string]attr.Value{"password": types.StringValue("changed")},
+			replace: path.Paths{path.Root("password")},
+		},
+		{
+			name:    "Changed store type",
+			config:  map[string]attr.Value{"store_type": types.StringValue("pkcs12")},
+			replace: path.Paths{path.Root("store_type")},
+		},
+		{
+			name:    "Changed deterministic",
+			config:  map[string]attr.Value{"deterministic": types.BoolValue(true)},
+			replace: path.Paths{path.Root("deterministic")},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.TODO()
+			_, crt := util.NewSelfSignedCertPEM(t)
+			r := NewKeystoreResource().(*KeystoreResource)
+			sch := keystoreResourceSchema(ctx, t)
+
+			// plan keystore with one trusted certificate
+			config := map[string]attr.Value{
+				"trusted_certificate": trustedCertificates(ctx, t, sch, "ca", crt),
+				"password":            types.StringValue("changeit"),
+				"store_type":          types.StringValue("jks"),
+			}
+			noState := tfsdk.State{Schema: sch, Raw: tftypes.NewValue(sch.Type().TerraformType(ctx), nil)}
+			planResp := planKeystore(ctx, t, r, sch, config, noState)
+			assert.Nil(t, planResp.RequiresReplace, "It should not replace new keystore")
+
+			// create
+			createResp := resource.CreateResponse{State: noState}
+			r.Create(ctx, resource.CreateRequest{Plan: planResp.Plan}, &createResp)
+			require.False(t, createResp.Diagnostics.HasError(), "It should create keystore: %v", createResp.Diagnostics)
+
+			var created KeystoreModel
+			require.False(t, createResp.State.Get(ctx, &created).HasError(), "It should read state")
+			ksData, err := base64.StdEncoding.DecodeString(created.KeystoreB64.ValueString())
+			require.NoError(t, err, "Keystore should be base 64 encoded")
+			ks, err := jks.Open(ksData, "changeit")
+			require.NoError(t, err, "It should open keystore")
+			assert.Equal(t, []string{"ca"}, ks.Aliases(), "Keystore should contain trusted certificate")
+			assert.Equal(t, created.KeystoreB64, created.JksB64, "It should save JKS keystore")
+			assert.True(t, created.Pkcs12B64.IsNull(), "It should only save JKS keystore")
+			assert.Contains(t, created.Entries.Elements(), "ca", "It should save entries")
+
+			// read
+			readResp := resource.ReadResponse{State: createResp.State}
+			r.Read(ctx, resource.ReadRequest{State: createResp.State}, &readResp)
+			require.False(t, readResp.Diagnostics.HasError(), "It should read keystore: %v", readResp.Diagnostics)
+			assert.True(t, readResp.State.Raw.Equal(createResp.State.Raw), "It should keep state")
+
+			// plan changes
+			for name, value := range tc.config {
+				config[name] = value
+			}
+			planResp = planKeystore(ctx, t, r, sch, config, createResp.State)
+			assert.Equal(t, tc.replace, planResp.RequiresReplace, "It should only replace keystore if it changes")
+			if tc.replace != nil {
+				return
+			}
+
+			// update
+			updateResp := resource.UpdateResponse{State: tfsdk.State{Schema: sch, Raw: planResp.Plan.Raw}}
+			r.Update(ctx, resource.UpdateRequest{Plan: planResp.Plan, State: createResp.State}, &updateResp)
+			require.False(t, updateResp.Diagnostics.HasError(), "It should update keystore: %v", updateResp.Diagnostics)
+
+			var updated KeystoreModel
+			require.False(t, updateResp.State.Get(ctx, &updated).HasError(), "It should read state")
+			assert.Equal(t, created.KeystoreB64, updated.KeystoreB64, "It should keep keystore")
+			assert.Equal(t, created.Entries, updated.Entries, "It should keep entries")
+			assert.Equal(t, config["min_remaining_validity"] != nil, !updated.MinValidity.IsNull(), "It should save planned inputs")
+		})
+	}
+}
+
+// Test that key pairs are only replaced when an attribute that changes the keystore changes.
+func TestKeyPairRequiresReplace(t *testing.T) {
+	ctx := context.TODO()
+	sch := keystoreResourceSchema(ctx, t)
+	keyPairsType, diags := sch.TypeAtPath(ctx, path.Root("key_pair"))
+	require.False(t, diags.HasError(), "It should return key pair type")
+	keyPairAttrTypes := keyPairsType.(types.SetType).ElemType.(types.ObjectType).AttrTypes
+
+	keyPairs := func(attrs map[string]attr.Value) types.Set {
+		keyPair := make(map[string]attr.Value, len(keyPairAttrTypes))
+		for name, attrType := range keyPairAttrTypes {
+			keyPair[name] = attrValue(ctx, t, attrType, nil)
+		}
+		keyPair["alias"] = types.StringValue("server")
+		keyPair["certificate"] = types.StringValue("certificate")
+		for name, value := range attrs {
+			keyPair[name] = value
+		}
+		return types.SetValueMust(
+			types.ObjectType{AttrTypes: keyPairAttrTypes},
+			[]attr.Value{types.ObjectValueMust(keyPairAttrTypes, keyPair)},
+		)
+	}
+
+	prior := keyPairs(map[string]attr.Value{
+		"trusted_roots":           types.StringValue("root"),
+		"strict_chain_validation": types.BoolValue(true),
+	})
+	for _, tc := range []struct {
+		name    string
+		planned types.Set
+		replace bool
+	}{
+		{
+			name: "Unchanged",
+			planned: keyPairs(map[string]attr.Value{
+				"trusted_roots":           types.StringValue("root"),
+				"strict_chain_validation": types.BoolValue(true),
+			}),
+		},
+		{
+			name: "Changed validation",
+			planned: keyPairs(map[string]attr.Value{
+				"trusted_roots":           types.StringValue("other root"),
+				"strict_chain_validation": types.BoolValue(false),
+			}),
+		},
+		{
+			name:    "Removed validation",
+			planned: keyPairs(nil),
+		},
+		{
+			name: "Changed certificate",
+			planned: keyPairs(map[string]attr.Value{
+				"certificate":             types.StringValue("other certificate"),
+				"trusted_roots":           types.StringValue("root"),
+				"strict_chain_validation": types.BoolValue(true),
+			}),
+			replace: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// only whether the resource exists is relevant
+			existing := tftypes.NewValue(tftypes.Object{}, map[string]tftypes.Value{})
+			req := planmodifier.SetRequest{
+				Path:       path.Root("key_pair"),
+				State:      tfsdk.State{Schema: sch, Raw: existing},
+				Plan:       tfsdk.Plan{Schema: sch, Raw: existing},
+				StateValue: prior,
+				PlanValue:  tc.planned,
+			}
+			var resp planmodifier.SetResponse
+			keyPairRequiresReplace().PlanModifySet(ctx, req, &resp)
+			require.False(t, resp.Diagnostics.HasError(), "It should modify plan: %v", resp.Diagnostics)
+			assert.Equal(t, tc.replace, resp.RequiresReplace, "It should only replace keystore if it changes")
+		})
+	}
+}
+
+// keystoreResourceSchema returns the schema of the jks_keystore resource.
+func keystoreResourceSchema(ctx context.Context, t *testing.T) schema.Schema {
+	var schemaResp resource.SchemaResponse
+	NewKeystoreResource().Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	require.False(t, schemaResp.Diagnostics.HasError(), "It should return schema")
+	return schemaResp.Schema
+}
+
+// planKeystore runs ModifyPlan for a keystore configuration, with computed values unknown as for a new resource.
+func planKeystore(ctx context.Context, t *testing.T, r *KeystoreResource, sch schema.Schema, config map[string]attr.Value, state tfsdk.State) resource.ModifyPlanResponse {
+	req := resource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: sch, Raw: newPlan(ctx, t, sch, config, nil).Raw},
+		Plan:   newPlan(ctx, t, sch, config, tftypes.UnknownValue),
+		State:  state,
+	}
+	resp := resource.ModifyPlanResponse{Plan: req.Plan}
+	r.ModifyPlan(ctx, req, &resp)
+	require.False(t, resp.Diagnostics.HasError(), "It should plan keystore: %v", resp.Diagnostics)
+	return resp
+}
+
+// newPlan returns a plan with the given root attribute values & every other attribute set from a raw Terraform
+// value, e.g. nil for null or tftypes.UnknownValue for computed values.
+func newPlan(ctx context.Context, t *testing.T, sch schema.Schema, values map[string]attr.Value, raw any) tfsdk.Plan {
+	plan := tfsdk.Plan{
+		Schema: sch,
+		Raw:    tftypes.NewValue(sch.Type().TerraformType(ctx), nil),
+	}
+	for name, attrType := range sch.Type().(types.ObjectType).AttrTypes {
+		value, ok := values[name]
+		if !ok {
+			value = attrValue(ctx, t, attrType, nil)
+			if sch.Attributes[name] != nil && sch.Attributes[name].IsComputed() {
+				value = attrValue(ctx, t, attrType, raw)
+			}
+		}
+		require.False(t, plan.SetAttribute(ctx, path.Root(name), value).HasError(), "It should plan %s", name)
+	}
+	return plan
+}
+
+// trustedCertificates returns a trusted_certificate block with a certificate.
+func trustedCertificates(ctx context.Context, t *testing.T, sch attributeTypes, alias string, crt []byte) types.Set {
+	certsType, diags := sch.TypeAtPath(ctx, path.Root("trusted_certificate"))
+	require.False(t, diags.HasError(), "It should return trusted certificate type")
+	certAttrTypes := certsType.(types.SetType).ElemType.(types.ObjectType).AttrTypes
+	certAttrs := map[string]attr.Value{
+		"alias":       types.StringValue(alias),
+		"certificate": types.StringValue(string(crt)),
+	}
+	for name, attrType := range certAttrTypes {
+		if _, ok := certAttrs[name]; !ok {
+			certAttrs[name] = attrValue(ctx, t, attrType, nil)
+		}
+	}
+	certs, diags := types.SetValue(
+		types.ObjectType{AttrTypes: certAttrTypes},
+		[]attr.Value{types.ObjectValueMust(certAttrTypes, certAttrs)},
+	)
+	require.False(t, diags.HasError(), "It should create trusted certificates")
+	return certs
+}
@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+)
+
+// attributeGetter reads attribute values, implemented by tfsdk.Config, tfsdk.Plan & tfsdk.State.
+type attributeGetter interface {
+	GetAttribute(ctx context.Context, p path.Path, target any) diag.Diagnostics
+}
+
+// attributeTypes looks up attribute types, implemented by the schemas of tfsdk.Config, tfsdk.Plan & tfsdk.State.
+type attributeTypes interface {
+	TypeAtPath(ctx context.Context, p path.Path) (attr.Type, diag.Diagnostics)
+}
+
+// getAttributes reads each field of the model pointed to by target from the root attribute named by its tfsdk tag.
+// Unlike Get, the schema may have attributes that aren't in the model & the model may have fields that aren't in
+// the schema, which are skipped, so models can be shared by schemas that extend each other.
+func getAttributes(ctx context.Context, src attributeGetter, sch attributeTypes, target any) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for name, field := range modelFields(target) {
+		attrPath := path.Root(name)
+		if _, typeDiags := sch.TypeAtPath(ctx, attrPath); typeDiags.HasError() {
+			continue
+		}
+		diags.Append(src.GetAttribute(ctx, attrPath, field.Addr().Interface())...)
+	}
+
+	return diags
+}
+
+// setAttributes writes each field of the model pointed to by val to the root attribute of state named by its tfsdk
+// tag, skipping fields that aren't in the state schema. See getAttributes.
+func setAttributes(ctx context.Context, state *tfsdk.State, val any) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for name, field := range modelFields(val) {
+		attrPath := path.Root(name)
+		if _, typeDiags := state.Schema.TypeAtPath(ctx, attrPath); typeDiags.HasError() {
+			continue
+		}
+		diags.Append(state.SetAttribute(ctx, attrPath, field.Interface())...)
+	}
+
+	return diags
+}
+
+// modelFields returns the fields of the struct pointed to by model by their tfsdk tags, skipping untagged fields &
+// fields tagged "-".
+func modelFields(model any) map[string]reflect.Value {
+	v := reflect.ValueOf(model).Elem()
+	fields := make(map[string]reflect.Value, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if name := v.Type().Field(i).Tag.Get("tfsdk"); name != "" && name != "-" {
+			fields[name] = v.Field(i)
+		}
+	}
+	return fields
+}
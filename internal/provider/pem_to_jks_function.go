@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// pemToJksAlias is the alias of the key pair in keystores generated by pem_to_jks.
+const pemToJksAlias = "certificate"
+
+func NewPemToJksFunction() function.Function {
+	return &PemToJksFunction{}
+}
+
+// PemToJksFunction defines the function implementation.
+// Functions must return the same result for the same arguments, so keystores are always built deterministically &
+// without time-based validity checks.
+type PemToJksFunction struct{}
+
+func (f *PemToJksFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "pem_to_jks"
+}
+
+func (f *PemToJksFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Converts a PEM certificate & private key to a JKS keystore.",
+		MarkdownDescription: "Generates a JKS keystore holding a single key pair with the alias `" + pemToJksAlias + "`, and returns it base 64 encoded. The keystore is built deterministically, so the same arguments always produce the same keystore, and the certificate is not checked for expiry. Use the `jks_keystore` resource for other store types, multiple entries or trusted certificates.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "certificate",
+				MarkdownDescription: "Certificate in PEM format. Any further certificates are added to the certificate chain before `chain`.",
+			},
+			function.StringParameter{
+				Name:                "private_key",
+				MarkdownDescription: "Unencrypted private key for the certificate in PEM format.",
+			},
+			function.StringParameter{
+				Name:                "chain",
+				MarkdownDescription: "Bundle of intermediate certificate authority certificates in PEM format, in chain order. May be empty.",
+			},
+			function.StringParameter{
+				Name:                "password",
+				MarkdownDescription: "Password for the keystore & private key.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *PemToJksFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var cert, key, chain, password string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &cert, &key, &chain, &password))
+	if resp.Error != nil {
+		return
+	}
+
+	// build keystore
+	bld := jks.NewKeystoreBuilder()
+	bld.SetPassword(password)
+	bld.SetDeterministic(true)
+	bld.SetSkipValidityChecks(true)
+	var caCerts [][]byte
+	if chain != "" {
+		caCerts = append(caCerts, []byte(chain))
+	}
+	bld.AddCert(pemToJksAlias, []byte(cert), []byte(key), caCerts...)
+	ksData, err := bld.Build()
+	if err != nil {
+		resp.Error = function.NewFuncError("Error creating keystore: " + err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, base64.StdEncoding.EncodeToString(ksData)))
+}
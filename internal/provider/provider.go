@@ -2,18 +2,38 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
 
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
+// passwordEnvVar is the environment variable holding the default keystore password.
+const passwordEnvVar = "JKS_PASSWORD"
+
 type JksProvider struct {
 	version string
 }
 
-type JksProviderModel struct{}
+// JksProviderModel describes the provider data model, which holds organisation-wide defaults. It's passed to data
+// sources & resources as provider data once the provider is configured.
+type JksProviderModel struct {
+	Password              types.String `tfsdk:"password"`
+	StoreType             types.String `tfsdk:"store_type"`
+	KeyPassword           types.String `tfsdk:"key_password"`
+	Deterministic         types.Bool   `tfsdk:"deterministic"`
+	StrictChainValidation types.Bool   `tfsdk:"strict_chain_validation"`
+}
 
 func (p *JksProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
 	resp.TypeName = "jks"
@@ -23,6 +43,31 @@ func (p *JksProvider) Metadata(ctx context.Context, req provider.MetadataRequest
 func (p *JksProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Terraform provider for working with JKS certificate stores",
+
+		Attributes: map[string]schema.Attribute{
+			"password": schema.StringAttribute{
+				Description: "Default keystore password, used when a data source or resource doesn't set `password`. Can also be set with the `" + passwordEnvVar + "` environment variable.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"store_type": schema.StringAttribute{
+				Description: "Default keystore format, used when a keystore doesn't set `store_type`. One of `jks`, `pkcs12`, `jceks`, `bks`, `bks-v1` or `uber`. Defaults to `jks`.",
+				Optional:    true,
+			},
+			"key_password": schema.StringAttribute{
+				Description: "Default password protecting private & secret keys in keystores, used when a `key_pair` or `secret_key` block doesn't set `key_password`. Defaults to the keystore password. Only applied when a keystore is generated, so changing it doesn't replace existing keystores, and not used to read keys with `jks_keystore_contents`, which takes `key_passwords` instead. Keys from a base keystore keep the keystore password.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"deterministic": schema.BoolAttribute{
+				Description: "Default for `deterministic`, used when a keystore doesn't set it. Defaults to `false`.",
+				Optional:    true,
+			},
+			"strict_chain_validation": schema.BoolAttribute{
+				Description: "Default for `strict_chain_validation` in `key_pair` blocks, i.e. whether a certificate chain that fails verification against `trusted_roots` is an error, rather than a warning. Defaults to `true`.",
+				Optional:    true,
+			},
+		},
 	}
 }
 
@@ -34,15 +79,66 @@ func (p *JksProvider) Configure(ctx context.Context, req provider.ConfigureReque
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	// defaults must be known before any keystore is generated
+	for name, value := range map[string]attr.Value{
+		"password":                data.Password,
+		"store_type":              data.StoreType,
+		"key_password":            data.KeyPassword,
+		"deterministic":           data.Deterministic,
+		"strict_chain_validation": data.StrictChainValidation,
+	} {
+		if value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(name),
+				"Unknown provider default",
+				fmt.Sprintf("The provider cannot be configured with an unknown value for %s. Set it to a known value, or leave it unset.", name),
+			)
+		}
+	}
+	if !data.StoreType.IsNull() && !isStoreType(data.StoreType.ValueString()) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("store_type"),
+			"Invalid store type",
+			fmt.Sprintf("Unsupported store type %q, must be one of jks, pkcs12, jceks, bks, bks-v1 or uber.", data.StoreType.ValueString()),
+		)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// read default password from environment
+	if data.Password.IsNull() {
+		if password, ok := os.LookupEnv(passwordEnvVar); ok {
+			data.Password = types.StringValue(password)
+		}
+	}
+
+	resp.DataSourceData = &data
+	resp.ResourceData = &data
 }
 
 func (p *JksProvider) Resources(ctx context.Context) []func() resource.Resource {
-	return []func() resource.Resource{}
+	return []func() resource.Resource{
+		NewKeystoreResource,
+		NewKeystoreFileResource,
+	}
 }
 
 func (p *JksProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewKeystoreDataSource,
+		NewTruststoreDataSource,
+		NewKeystoreContentsDataSource,
+	}
+}
+
+func (p *JksProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewPemToJksFunction,
+		NewJksToPkcs12Function,
+		NewAliasesFunction,
+		NewFingerprintFunction,
 	}
 }
 
@@ -53,3 +149,99 @@ func New(version string) func() provider.Provider {
 		}
 	}
 }
+
+// providerDefaults returns the provider defaults from the provider data passed to a data source or resource. The
+// defaults are nil until the provider is configured, e.g. during static validation.
+func providerDefaults(providerData any) (*JksProviderModel, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if providerData == nil {
+		return nil, diags
+	}
+	defaults, ok := providerData.(*JksProviderModel)
+	if !ok {
+		diags.AddError(
+			"Unexpected provider data",
+			fmt.Sprintf("Expected *JksProviderModel, got %T.", providerData),
+		)
+	}
+	return defaults, diags
+}
+
+// requirePassword returns an error if a password is set by neither the configuration nor the provider defaults.
+func requirePassword(password types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if password.IsNull() {
+		diags.AddAttributeError(
+			path.Root("password"),
+			"Missing password",
+			fmt.Sprintf("Set password, or set a default password in the provider configuration or with the %s environment variable.", passwordEnvVar),
+		)
+	}
+	return diags
+}
+
+// secretKeyStoreTypes lists the store types that can hold secret keys, e.g. "pkcs12, jceks or bks".
+func secretKeyStoreTypes() string {
+	names := make([]string, 0, len(jks.SecretKeyStoreTypes))
+	for _, storeType := range jks.SecretKeyStoreTypes {
+		names = append(names, string(storeType))
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// isStoreType reports whether a store type is supported.
+func isStoreType(storeType string) bool {
+	switch jks.StoreType(storeType) {
+	case jks.StoreTypeJKS, jks.StoreTypePKCS12, jks.StoreTypeJCEKS, jks.StoreTypeBKS, jks.StoreTypeBKSV1, jks.StoreTypeUBER:
+		return true
+	}
+	return false
+}
+
+// password returns a password, or the default password if it's not set.
+func (p *JksProviderModel) password(password types.String) types.String {
+	if password.IsNull() && p != nil {
+		return p.Password
+	}
+	return password
+}
+
+// storeType returns a store type, or the default store type if it's not set, falling back to JKS.
+func (p *JksProviderModel) storeType(storeType types.String) types.String {
+	if !storeType.IsNull() {
+		return storeType
+	}
+	if p != nil && !p.StoreType.IsNull() {
+		return p.StoreType
+	}
+	return types.StringValue(string(jks.StoreTypeJKS))
+}
+
+// deterministic returns whether deterministic builds are enabled, or the default if it's not set. The result is
+// null if neither is set.
+func (p *JksProviderModel) deterministic(deterministic types.Bool) types.Bool {
+	if deterministic.IsNull() && p != nil {
+		return p.Deterministic
+	}
+	return deterministic
+}
+
+// keyPassword returns a key password, or the default key password if it's not set. An empty result means the key
+// is protected with the keystore password.
+func (p *JksProviderModel) keyPassword(keyPassword types.String) string {
+	if keyPassword.ValueString() == "" && p != nil {
+		return p.KeyPassword.ValueString()
+	}
+	return keyPassword.ValueString()
+}
+
+// isStrict reports whether strict chain validation is enabled, from the key pair setting, the provider default,
+// or true if neither is set.
+func (p *JksProviderModel) isStrict(strict types.Bool) bool {
+	if strict.IsNull() && p != nil && !p.StrictChainValidation.IsNull() {
+		return p.StrictChainValidation.ValueBool()
+	}
+	return strict.IsNull() || strict.ValueBool()
+}
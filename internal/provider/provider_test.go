@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/fhke/terraform-provider-jks/test/util"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the provider defaults are validated, and the default password is read from the environment.
+func TestProviderConfigure(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		env      *string
+		config   map[string]attr.Value
+		password types.String
+		errPath  *path.Path
+	}{
+		{
+			name:     "Password from environment",
+			env:      ptr("env-secret"),
+			password: types.StringValue("env-secret"),
+		},
+		{
+			name:     "Configured password",
+			env:      ptr("env-secret"),
+			config:   map[string]attr.Value{"password": types.StringValue("secret")},
+			password: types.StringValue("secret"),
+		},
+		{
+			name:     "No password",
+			password: types.StringNull(),
+		},
+		{
+			name:     "Valid store type",
+			config:   map[string]attr.Value{"store_type": types.StringValue("bks-v1")},
+			password: types.StringNull(),
+		},
+		{
+			name:    "Invalid store type",
+			config:  map[string]attr.Value{"store_type": types.StringValue("p12")},
+			errPath: ptr(path.Root("store_type")),
+		},
+		{
+			name:    "Unknown default",
+			config:  map[string]attr.Value{"key_password": types.StringUnknown()},
+			errPath: ptr(path.Root("key_password")),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.TODO()
+			t.Setenv(passwordEnvVar, "")
+			if tc.env != nil {
+				t.Setenv(passwordEnvVar, *tc.env)
+			} else {
+				require.NoError(t, os.Unsetenv(passwordEnvVar), "It should unset %s", passwordEnvVar)
+			}
+
+			p := New("test")()
+			var schemaResp provider.SchemaResponse
+			p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+			require.False(t, schemaResp.Diagnostics.HasError(), "It should return schema")
+			sch := schemaResp.Schema
+
+			// configure provider, leaving unset defaults null
+			var resp provider.ConfigureResponse
+			p.Configure(ctx, provider.ConfigureRequest{Config: newConfig(ctx, t, tfsdk.Config{Schema: sch}, tc.config)}, &resp)
+
+			if tc.errPath != nil {
+				require.True(t, resp.Diagnostics.HasError(), "It should reject configuration")
+				assert.Equal(t, *tc.errPath, resp.Diagnostics.Errors()[0].(diag.DiagnosticWithPath).Path(), "Error should be for attribute")
+				assert.Nil(t, resp.ResourceData, "It should not pass defaults to resources")
+				return
+			}
+			require.False(t, resp.Diagnostics.HasError(), "It should configure provider: %v", resp.Diagnostics)
+			defaults, diags := providerDefaults(resp.ResourceData)
+			require.False(t, diags.HasError(), "It should pass defaults to resources")
+			assert.Equal(t, resp.ResourceData, resp.DataSourceData, "It should pass defaults to data sources")
+			assert.Equal(t, tc.password, defaults.Password, "Default password should match")
+		})
+	}
+}
+
+// Test that provider defaults are planned for keystores that don't set them, and replace keystores when changed.
+func TestProviderDefaults(t *testing.T) {
+	defaults := JksProviderModel{
+		Password:      types.StringValue("default-secret"),
+		StoreType:     types.StringValue("pkcs12"),
+		Deterministic: types.BoolValue(true),
+	}
+
+	for _, tc := range []struct {
+		name     string
+		config   map[string]attr.Value
+		defaults func(defaults *JksProviderModel)
+		replace  path.Paths
+	}{
+		{
+			name:     "Unchanged defaults",
+			defaults: func(defaults *JksProviderModel) {},
+		},
+		{
+			name:     "Changed key password",
+			defaults: func(defaults *JksProviderModel) { defaults.KeyPassword = types.StringValue("key-secret") },
+		},
+		{
+			name:     "Changed password",
+			defaults: func(defaults *JksProviderModel) { defaults.Password = types.StringValue("changed") },
+			replace:  path.Paths{path.Root("password")},
+		},
+		{
+			name:     "Changed store type",
+			defaults: func(defaults *JksProviderModel) { defaults.StoreType = types.StringValue("jceks") },
+			replace:  path.Paths{path.Root("store_type")},
+		},
+		{
+			name:     "Removed defaults",
+			defaults: func(defaults *JksProviderModel) { *defaults = JksProviderModel{Password: defaults.Password} },
+			replace:  path.Paths{path.Root("store_type"), path.Root("deterministic")},
+		},
+		{
+			name:     "Changed default set in keystore",
+			config:   map[string]attr.Value{"store_type": types.StringValue("pkcs12")},
+			defaults: func(defaults *JksProviderModel) { defaults.StoreType = types.StringValue("jceks") },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.TODO()
+			_, crt := util.NewSelfSignedCertPEM(t)
+			r := &KeystoreResource{defaults: &defaults}
+			sch := keystoreResourceSchema(ctx, t)
+
+			// plan & create keystore with defaults
+			config := map[string]attr.Value{
+				"trusted_certificate": trustedCertificates(ctx, t, sch, "ca", crt),
+			}
+			for name, value := range tc.config {
+				config[name] = value
+			}
+			noState := tfsdk.State{Schema: sch, Raw: tftypes.NewValue(sch.Type().TerraformType(ctx), nil)}
+			planResp := planKeystore(ctx, t, r, sch, config, noState)
+
+			var planned KeystoreModel
+			require.False(t, planResp.Plan.Get(ctx, &planned).HasError(), "It should read plan")
+			assert.Equal(t, defaults.Password, planned.Password, "It should plan default password")
+			assert.Equal(t, defaults.StoreType, planned.StoreType, "It should plan default store type")
+			assert.Equal(t, defaults.Deterministic, planned.Deterministic, "It should plan default deterministic")
+
+			createResp := resource.CreateResponse{State: noState}
+			r.Create(ctx, resource.CreateRequest{Plan: planResp.Plan}, &createResp)
+			require.False(t, createResp.Diagnostics.HasError(), "It should create keystore: %v", createResp.Diagnostics)
+
+			// plan with changed defaults
+			changed := defaults
+			tc.defaults(&changed)
+			r.defaults = &changed
+			planResp = planKeystore(ctx, t, r, sch, config, createResp.State)
+			assert.ElementsMatch(t, tc.replace, planResp.RequiresReplace, "It should only replace keystore if a default it uses changes")
+		})
+	}
+}
+
+// Test the provider defaults for key pairs, which aren't planned.
+func TestProviderKeyPairDefaults(t *testing.T) {
+	var unset *JksProviderModel
+	defaults := &JksProviderModel{
+		KeyPassword:           types.StringValue("default-secret"),
+		StrictChainValidation: types.BoolValue(false),
+	}
+
+	assert.Equal(t, "", unset.keyPassword(types.StringNull()), "Key password should default to keystore password")
+	assert.Equal(t, "default-secret", defaults.keyPassword(types.StringNull()), "Key password should default to provider key password")
+	assert.Equal(t, "secret", defaults.keyPassword(types.StringValue("secret")), "Key password should override provider key password")
+
+	assert.True(t, unset.isStrict(types.BoolNull()), "Chain validation should default to strict")
+	assert.True(t, (&JksProviderModel{}).isStrict(types.BoolNull()), "Chain validation should default to strict")
+	assert.False(t, defaults.isStrict(types.BoolNull()), "Chain validation should default to provider setting")
+	assert.True(t, defaults.isStrict(types.BoolValue(true)), "Chain validation should override provider setting")
+}
+
+// newConfig returns a configuration for the schema of config with the given root attribute values & every other
+// attribute null.
+func newConfig(ctx context.Context, t *testing.T, config tfsdk.Config, values map[string]attr.Value) tfsdk.Config {
+	state := tfsdk.State{
+		Schema: config.Schema,
+		Raw:    tftypes.NewValue(config.Schema.Type().TerraformType(ctx), nil),
+	}
+	for name, attrType := range config.Schema.Type().(types.ObjectType).AttrTypes {
+		value, ok := values[name]
+		if !ok {
+			value = attrValue(ctx, t, attrType, nil)
+		}
+		require.False(t, state.SetAttribute(ctx, path.Root(name), value).HasError(), "It should configure %s", name)
+	}
+	config.Raw = state.Raw
+	return config
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
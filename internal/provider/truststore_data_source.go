@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func NewTruststoreDataSource() datasource.DataSource {
+	return &TruststoreDataSource{}
+}
+
+// TruststoreDataSource defines the data source implementation.
+type TruststoreDataSource struct {
+	// defaults are the provider defaults, or nil if the provider is not configured yet.
+	defaults *JksProviderModel
+}
+
+// TruststoreDataSourceModel describes the data source data model.
+type TruststoreDataSourceModel struct {
+	// Input values
+	PEMBundle     types.String `tfsdk:"pem_bundle"`
+	Password      types.String `tfsdk:"password"`
+	Deterministic types.Bool   `tfsdk:"deterministic"`
+	// Computed values
+	Aliases types.List   `tfsdk:"aliases"`
+	JksB64  types.String `tfsdk:"jks_base64"`
+}
+
+func (d *TruststoreDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_truststore"
+}
+
+func (d *TruststoreDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	var diags diag.Diagnostics
+	d.defaults, diags = providerDefaults(req.ProviderData)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (d *TruststoreDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Generates a JKS truststore from a bundle of PEM certificates. Each certificate is added as a trusted certificate entry, with an alias generated from its subject common name & SHA-256 fingerprint. Duplicate certificates are skipped.",
+
+		Attributes: map[string]schema.Attribute{
+			"pem_bundle": schema.StringAttribute{
+				Description: "Concatenated certificates in PEM format, e.g. the contents of a CA bundle file. PEM blocks other than certificates are ignored.",
+				Required:    true,
+			},
+			"password": schema.StringAttribute{
+				Description: "Password for truststore. Defaults to the provider `password`, one of which must be set.",
+				Optional:    true,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"deterministic": schema.BoolAttribute{
+				Description: "Produce identical output for identical inputs, by using certificate validity start times as entry timestamps. Defaults to the provider `deterministic`, or `false`.",
+				Optional:    true,
+				Computed:    true,
+			},
+			"aliases": schema.ListAttribute{
+				Description: "Generated aliases of the certificates in the truststore, in bundle order.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"jks_base64": schema.StringAttribute{
+				Description: "Base 64 encoded truststore, in JKS format",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (d *TruststoreDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TruststoreDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// apply provider defaults
+	data.Password = d.defaults.password(data.Password)
+	data.Deterministic = d.defaults.deterministic(data.Deterministic)
+	resp.Diagnostics.Append(requirePassword(data.Password)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// create jks builder
+	bld := jks.NewKeystoreBuilder()
+	bld.SetPassword(data.Password.ValueString())
+	bld.SetDeterministic(data.Deterministic.ValueBool())
+
+	// add certs from bundle
+	aliases, err := bld.AddTrustedCertBundle([]byte(data.PEMBundle.ValueString()))
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("pem_bundle"),
+			"Error reading PEM bundle",
+			err.Error(),
+		)
+		return
+	}
+
+	// build jks truststore
+	jksData, err := bld.Build()
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error creating JKS truststore",
+			err.Error(),
+		)
+		return
+	}
+
+	// add aliases & base64 encoded jks to model
+	var diags diag.Diagnostics
+	data.Aliases, diags = types.ListValueFrom(ctx, types.StringType, aliases)
+	resp.Diagnostics.Append(diags...)
+	data.JksB64 = types.StringValue(base64.StdEncoding.EncodeToString(jksData))
+
+	// save model
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
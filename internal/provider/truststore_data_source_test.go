@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/fhke/terraform-provider-jks/jks"
+	"github.com/fhke/terraform-provider-jks/test/util"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that a truststore is generated from a PEM bundle, with an entry per unique certificate.
+func TestTruststoreDataSource(t *testing.T) {
+	key, crt := util.NewSelfSignedCertPEM(t)
+	_, caCrt := util.NewSelfSignedCertPEM(t)
+	bundle := string(crt) + string(key) + string(caCrt) + string(crt)
+
+	for _, tc := range []struct {
+		name     string
+		config   map[string]attr.Value
+		defaults *JksProviderModel
+		password string
+		err      string
+	}{
+		{
+			name: "Bundle",
+			config: map[string]attr.Value{
+				"pem_bundle": types.StringValue(bundle),
+				"password":   types.StringValue("changeit"),
+			},
+			password: "changeit",
+		},
+		{
+			name:     "Default password",
+			config:   map[string]attr.Value{"pem_bundle": types.StringValue(bundle)},
+			defaults: &JksProviderModel{Password: types.StringValue("default-secret")},
+			password: "default-secret",
+		},
+		{
+			name:   "Missing password",
+			config: map[string]attr.Value{"pem_bundle": types.StringValue(bundle)},
+			err:    "Missing password",
+		},
+		{
+			name: "No certificates",
+			config: map[string]attr.Value{
+				"pem_bundle": types.StringValue(string(key)),
+				"password":   types.StringValue("changeit"),
+			},
+			err: "Error reading PEM bundle",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.TODO()
+			d := &TruststoreDataSource{defaults: tc.defaults}
+			var schemaResp datasource.SchemaResponse
+			d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+			require.False(t, schemaResp.Diagnostics.HasError(), "It should return schema")
+			sch := schemaResp.Schema
+
+			resp := datasource.ReadResponse{State: tfsdk.State{
+				Schema: sch,
+				Raw:    tftypes.NewValue(sch.Type().TerraformType(ctx), nil),
+			}}
+			d.Read(ctx, datasource.ReadRequest{Config: newConfig(ctx, t, tfsdk.Config{Schema: sch}, tc.config)}, &resp)
+			if tc.err != "" {
+				require.True(t, resp.Diagnostics.HasError(), "It should fail")
+				assert.Equal(t, tc.err, resp.Diagnostics.Errors()[0].Summary(), "Error should describe failure")
+				return
+			}
+			require.False(t, resp.Diagnostics.HasError(), "It should read truststore: %v", resp.Diagnostics)
+
+			var data TruststoreDataSourceModel
+			require.False(t, resp.State.Get(ctx, &data).HasError(), "It should read state")
+			var aliases []string
+			require.False(t, data.Aliases.ElementsAs(ctx, &aliases, false).HasError(), "Aliases should be a list of strings")
+			require.Len(t, aliases, 2, "Duplicate certificates should be skipped")
+			assert.NotEqual(t, aliases[0], aliases[1], "Aliases should be unique")
+
+			// truststore holds the certificates under the generated aliases
+			ksData, err := base64.StdEncoding.DecodeString(data.JksB64.ValueString())
+			require.NoError(t, err, "Truststore should be base 64 encoded")
+			ks, err := jks.Open(ksData, tc.password)
+			require.NoError(t, err, "It should open truststore")
+			assert.Equal(t, jks.StoreTypeJKS, ks.Type, "Truststore should be JKS")
+			assert.ElementsMatch(t, aliases, ks.Aliases(), "Aliases should match truststore")
+			for i, wantCrt := range [][]byte{crt, caCrt} {
+				for _, entry := range ks.Entries {
+					if entry.Alias == aliases[i] {
+						assert.Equal(t, jks.EntryTypeTrustedCert, entry.Type, "Entry should be a trusted certificate")
+						assert.Equal(t, [][]byte{wantCrt}, entry.CertificateChainPEM(), "Certificates should be in bundle order")
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package jks
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxAliasLen is the maximum length of an alias, in characters.
+	maxAliasLen = 255
+	// maxGeneratedAliasNameLen is the maximum length of the name part of a generated alias.
+	maxGeneratedAliasNameLen = 64
+)
+
+// NormalizeAlias returns the form of an alias used to compare aliases. Java keystores treat aliases as
+// case-insensitive & lowercase them, so aliases that differ only in case refer to the same entry.
+func NormalizeAlias(alias string) string {
+	return strings.ToLower(alias)
+}
+
+// ValidateAlias checks that an alias is non-empty valid UTF-8, at most 255 characters long, contains no control
+// characters & has no leading or trailing whitespace. Returns an error wrapping ErrInvalidAlias otherwise.
+func ValidateAlias(alias string) error {
+	switch {
+	case alias == "":
+		return fmt.Errorf("%w: alias must not be an empty string", ErrInvalidAlias)
+	case !utf8.ValidString(alias):
+		return fmt.Errorf("%w: alias %q is not valid UTF-8", ErrInvalidAlias, alias)
+	case utf8.RuneCountInString(alias) > maxAliasLen:
+		return fmt.Errorf("%w: alias %q is longer than %d characters", ErrInvalidAlias, alias, maxAliasLen)
+	case strings.IndexFunc(alias, unicode.IsControl) >= 0:
+		return fmt.Errorf("%w: alias %q contains control characters", ErrInvalidAlias, alias)
+	case strings.TrimSpace(alias) != alias:
+		return fmt.Errorf("%w: alias %q has leading or trailing whitespace", ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+// checkAliasCollisions returns an error wrapping ErrDuplicateAlias if any aliases are equal after normalisation.
+func checkAliasCollisions(aliases []string) error {
+	seen := make(map[string]string, len(aliases))
+	for _, alias := range aliases {
+		normalized := NormalizeAlias(alias)
+		if prev, ok := seen[normalized]; ok {
+			if prev == alias {
+				return fmt.Errorf("%w: alias %q is used by more than one entry", ErrDuplicateAlias, alias)
+			}
+			return fmt.Errorf("%w: aliases %q & %q collide, as keystore aliases are case-insensitive", ErrDuplicateAlias, prev, alias)
+		}
+		seen[normalized] = alias
+	}
+	return nil
+}
+
+// generateAlias generates an alias for a certificate from its subject & fingerprint.
+// Aliases take the form "<name>-<fingerprint>", where name is the lowercased subject common name (falling back
+// to the organisation) with any characters other than letters & digits replaced by hyphens, and fingerprint
+// is the first 8 hex characters of the certificate's SHA-256 fingerprint.
+func generateAlias(cert *x509.Certificate) string {
+	name := cert.Subject.CommonName
+	if name == "" && len(cert.Subject.Organization) > 0 {
+		name = cert.Subject.Organization[0]
+	}
+
+	// normalise name
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+	name = strings.Trim(collapseHyphens(name), "-")
+	if len(name) > maxGeneratedAliasNameLen {
+		name = strings.TrimRight(name[:maxGeneratedAliasNameLen], "-")
+	}
+	if name == "" {
+		name = "cert"
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	return name + "-" + hex.EncodeToString(fingerprint[:4])
+}
+
+// collapseHyphens replaces runs of hyphens with a single hyphen.
+func collapseHyphens(s string) string {
+	for strings.Contains(s, "--") {
+		s = strings.ReplaceAll(s, "--", "-")
+	}
+	return s
+}
@@ -0,0 +1,219 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lwithers/minijks/jks"
+	"golang.org/x/crypto/twofish"
+)
+
+const (
+	// bksTagEnd terminates the list of entries in a BouncyCastle store.
+	bksTagEnd byte = 0
+	// bksTagCert marks a trusted certificate entry.
+	bksTagCert byte = 1
+	// bksTagKey marks an unencrypted key entry.
+	bksTagKey byte = 2
+	// bksTagSealed marks an encrypted key entry.
+	bksTagSealed byte = 4
+	// bksKeyTypePrivate marks an encoded key as a private key.
+	bksKeyTypePrivate byte = 0
+	// bksKeyTypeSecret marks an encoded key as a secret key.
+	bksKeyTypeSecret byte = 2
+	// bksSaltLen is the salt length used by BouncyCastle for stores & keys.
+	bksSaltLen = 20
+	// bksIterations is the iteration count for stores & keys, the maximum that BouncyCastle generates itself.
+	bksIterations = 1024 + 0x3ff
+)
+
+// packBKS serialises trusted certificates, key pairs & secret keys to a BouncyCastle BKS or UBER keystore.
+// BKS stores are integrity protected with HMAC-SHA1, UBER stores are encrypted with Twofish. In both formats,
+// private & secret keys are sealed with PBEWithSHAAnd3-KeyTripleDES-CBC.
+func (k *KeystoreBuilder) packBKS(certs []*jks.Cert, keyPairs []*jks.Keypair, secretKeys []*secretKeyEntry) ([]byte, error) {
+	var body bytes.Buffer
+
+	// write entries
+	for _, cert := range certs {
+		if err := writeBKSTrustedCert(&body, cert); err != nil {
+			return nil, fmt.Errorf("error writing trusted certificate %q: %w", cert.Alias, err)
+		}
+	}
+	for _, kp := range keyPairs {
+		if err := k.writeBKSKeyPair(&body, kp); err != nil {
+			return nil, fmt.Errorf("error writing key pair %q: %w", kp.Alias, err)
+		}
+	}
+	for _, sk := range secretKeys {
+		if err := writeBKSEntryHeader(&body, bksTagSealed, sk.Alias, sk.Timestamp, nil); err != nil {
+			return nil, fmt.Errorf("error writing secret key %q: %w", sk.Alias, err)
+		}
+		if err := k.writeBKSSealedKey(&body, sk.Alias, bksKeyTypeSecret, "RAW", sk.Algorithm, sk.Key); err != nil {
+			return nil, fmt.Errorf("error writing secret key %q: %w", sk.Alias, err)
+		}
+	}
+	body.WriteByte(bksTagEnd)
+
+	// write header
+	version := uint32(2)
+	if k.storeType != StoreTypeBKS {
+		version = 1
+	}
+	salt, err := k.salt(bksSaltLen, []byte("bks store"), body.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error generating salt: %w", err)
+	}
+	var buf bytes.Buffer
+	writeUint32(&buf, version)
+	writeBytes(&buf, salt)
+	writeUint32(&buf, bksIterations)
+
+	password := pkcs12Password(k.password)
+
+	if k.storeType == StoreTypeUBER {
+		// append SHA-1 digest of entries & encrypt
+		digest := sha1.Sum(body.Bytes())
+		body.Write(digest[:])
+
+		block, err := twofish.NewCipher(pkcs12KDF(sha1.New, 64, password, salt, bksIterations, pkcs12KDFKeyID, 32))
+		if err != nil {
+			return nil, err
+		}
+		iv := pkcs12KDF(sha1.New, 64, password, salt, bksIterations, pkcs12KDFIVID, block.BlockSize())
+		ciphertext := pkcs7Pad(body.Bytes(), block.BlockSize())
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+		buf.Write(ciphertext)
+
+		return buf.Bytes(), nil
+	}
+
+	// append HMAC-SHA1 of entries. BKS v1 derives the MAC key from its size in bits / 8, rather than bytes.
+	macKeyLen := sha1.Size
+	if version == 1 {
+		macKeyLen = sha1.Size / 8
+	}
+	mac := hmac.New(sha1.New, pkcs12KDF(sha1.New, 64, password, salt, bksIterations, pkcs12KDFMACID, macKeyLen))
+	mac.Write(body.Bytes())
+	buf.Write(body.Bytes())
+	buf.Write(mac.Sum(nil))
+
+	return buf.Bytes(), nil
+}
+
+// writeBKSTrustedCert writes a trusted certificate entry.
+func writeBKSTrustedCert(w io.Writer, cert *jks.Cert) error {
+	if err := writeBKSEntryHeader(w, bksTagCert, cert.Alias, cert.Timestamp, nil); err != nil {
+		return err
+	}
+	return writeCert(w, cert.Cert)
+}
+
+// writeBKSKeyPair writes a sealed private key entry & its certificate chain.
+func (k *KeystoreBuilder) writeBKSKeyPair(w io.Writer, kp *jks.Keypair) error {
+	chain := make([]*x509.Certificate, len(kp.CertChain))
+	for i, cert := range kp.CertChain {
+		chain[i] = cert.Cert
+	}
+	if err := writeBKSEntryHeader(w, bksTagSealed, kp.Alias, kp.Timestamp, chain); err != nil {
+		return err
+	}
+
+	// encode private key
+	plaintext, err := x509.MarshalPKCS8PrivateKey(kp.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("error marshalling private key: %w", err)
+	}
+	algorithm, err := javaKeyAlgorithm(kp.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	return k.writeBKSSealedKey(w, kp.Alias, bksKeyTypePrivate, "PKCS#8", algorithm, plaintext)
+}
+
+// writeBKSSealedKey encodes a key with its type, format & algorithm, then writes it sealed with the key password.
+func (k *KeystoreBuilder) writeBKSSealedKey(w io.Writer, alias string, keyType byte, format, algorithm string, encoded []byte) error {
+	var encKey bytes.Buffer
+	encKey.WriteByte(keyType)
+	if err := writeStr(&encKey, format); err != nil {
+		return err
+	}
+	if err := writeStr(&encKey, algorithm); err != nil {
+		return err
+	}
+	writeBytes(&encKey, encoded)
+
+	// seal encoded key
+	salt, err := k.salt(bksSaltLen, []byte("bks key"), []byte(alias), encoded)
+	if err != nil {
+		return fmt.Errorf("error generating salt: %w", err)
+	}
+	ciphertext, err := bksSealKey(encKey.Bytes(), k.keyPassword(alias), salt)
+	if err != nil {
+		return fmt.Errorf("error sealing key: %w", err)
+	}
+
+	var sealed bytes.Buffer
+	writeBytes(&sealed, salt)
+	writeUint32(&sealed, bksIterations)
+	sealed.Write(ciphertext)
+	writeBytes(w, sealed.Bytes())
+
+	return nil
+}
+
+// writeBKSEntryHeader writes the type, alias, timestamp & certificate chain common to all entries.
+func writeBKSEntryHeader(w io.Writer, tag byte, alias string, ts time.Time, chain []*x509.Certificate) error {
+	_, _ = w.Write([]byte{tag})
+	if err := writeStr(w, alias); err != nil {
+		return fmt.Errorf("error writing alias: %w", err)
+	}
+	writeTimestamp(w, ts)
+	writeUint32(w, uint32(len(chain)))
+	for _, cert := range chain {
+		if err := writeCert(w, cert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bksSealKey encrypts an encoded key with PBEWithSHAAnd3-KeyTripleDES-CBC.
+func bksSealKey(plaintext []byte, password string, salt []byte) ([]byte, error) {
+	pw := pkcs12Password(password)
+	key := pkcs12KDF(sha1.New, 64, pw, salt, bksIterations, pkcs12KDFKeyID, 24)
+	iv := pkcs12KDF(sha1.New, 64, pw, salt, bksIterations, pkcs12KDFIVID, des.BlockSize)
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := pkcs7Pad(plaintext, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+
+	return ciphertext, nil
+}
+
+// javaKeyAlgorithm returns the Java algorithm name for a private key.
+func javaKeyAlgorithm(key any) (string, error) {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return "RSA", nil
+	case *ecdsa.PrivateKey:
+		return "EC", nil
+	case ed25519.PrivateKey:
+		return "Ed25519", nil
+	default:
+		return "", fmt.Errorf("unsupported private key type %T", key)
+	}
+}
@@ -0,0 +1,193 @@
+package jks
+
+import (
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/x509"
+	"fmt"
+
+	"golang.org/x/crypto/twofish"
+)
+
+// unpackBKS decodes a BouncyCastle BKS or UBER keystore. Both formats share a version 1 header, so a store
+// that fails BKS integrity checks is tried as UBER.
+func unpackBKS(data []byte, password string, keyPasswords map[string]string) (*Keystore, error) {
+	// read header
+	r := newReader(data)
+	version := r.uint32()
+	salt := r.bytes()
+	iterations := int(r.uint32())
+	if r.err != nil {
+		return nil, r.err
+	}
+	if iterations < 1 || iterations > maxPBEIterations {
+		return nil, fmt.Errorf("invalid iteration count %d", iterations)
+	}
+	rest := data[len(data)-r.r.Len():]
+	pw := pkcs12Password(password)
+
+	// BKS stores end with an HMAC-SHA1 of the entries
+	if len(rest) >= sha1.Size {
+		body, sum := rest[:len(rest)-sha1.Size], rest[len(rest)-sha1.Size:]
+		storeType, macKeyLen := StoreTypeBKS, sha1.Size
+		if version == 1 {
+			storeType, macKeyLen = StoreTypeBKSV1, sha1.Size/8
+		}
+		mac := hmac.New(sha1.New, pkcs12KDF(sha1.New, 64, pw, salt, iterations, pkcs12KDFMACID, macKeyLen))
+		mac.Write(body)
+		if hmac.Equal(mac.Sum(nil), sum) {
+			return unpackBKSEntries(body, password, keyPasswords, storeType)
+		}
+	}
+
+	// UBER stores encrypt the entries & their SHA-1 digest with Twofish
+	if version == 1 {
+		block, err := twofish.NewCipher(pkcs12KDF(sha1.New, 64, pw, salt, iterations, pkcs12KDFKeyID, 32))
+		if err != nil {
+			return nil, err
+		}
+		iv := pkcs12KDF(sha1.New, 64, pw, salt, iterations, pkcs12KDFIVID, block.BlockSize())
+		plaintext, err := cbcDecrypt(block, iv, rest)
+		if err == nil && len(plaintext) >= sha1.Size {
+			body, sum := plaintext[:len(plaintext)-sha1.Size], plaintext[len(plaintext)-sha1.Size:]
+			if digest := sha1.Sum(body); hmac.Equal(digest[:], sum) {
+				return unpackBKSEntries(body, password, keyPasswords, StoreTypeUBER)
+			}
+		}
+	}
+
+	return nil, ErrIntegrityCheck
+}
+
+// unpackBKSEntries decodes the entries of a BKS or UBER keystore, after integrity checks.
+func unpackBKSEntries(body []byte, password string, keyPasswords map[string]string, storeType StoreType) (*Keystore, error) {
+	r := newReader(body)
+	ks := &Keystore{Type: storeType}
+
+	for tag := r.uint8(); tag != bksTagEnd && r.err == nil; tag = r.uint8() {
+		entry := &Entry{
+			Alias:   r.str(),
+			Created: r.timestamp(),
+		}
+		chain := r.certChain()
+
+		switch tag {
+		case bksTagCert:
+			entry.Type = EntryTypeTrustedCert
+			entry.CertificateChain = []*x509.Certificate{r.cert()}
+		case bksTagKey:
+			entry.CertificateChain = chain
+			key, err := decodeBKSKey(r)
+			if err != nil {
+				return nil, fmt.Errorf("error reading key %q: %w", entry.Alias, err)
+			}
+			if err := setBKSKey(entry, key, parsePKCS8Key); err != nil {
+				return nil, fmt.Errorf("error parsing private key %q: %w", entry.Alias, err)
+			}
+		case bksTagSealed:
+			entry.CertificateChain = chain
+			sealed := r.bytes()
+			if r.err != nil {
+				break
+			}
+			key, err := bksUnsealKey(sealed, keyPassword(keyPasswords, entry.Alias, password))
+			if err != nil {
+				return nil, fmt.Errorf("error decrypting key %q: %w", entry.Alias, err)
+			}
+			if err := setBKSKey(entry, key, parsePKCS8); err != nil {
+				return nil, fmt.Errorf("error parsing private key %q: %w", entry.Alias, err)
+			}
+		default:
+			if r.err == nil {
+				return nil, fmt.Errorf("unsupported entry type %d for alias %q", tag, entry.Alias)
+			}
+		}
+
+		ks.Entries = append(ks.Entries, entry)
+	}
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// bksUnsealKey decrypts a sealed key entry, returning the encoded key.
+func bksUnsealKey(sealed []byte, password string) (*bksKey, error) {
+	r := newReader(sealed)
+	salt := r.bytes()
+	iterations := int(r.uint32())
+	if r.err != nil {
+		return nil, r.err
+	}
+	if iterations < 1 || iterations > maxPBEIterations {
+		return nil, fmt.Errorf("invalid iteration count %d", iterations)
+	}
+	ciphertext := sealed[len(sealed)-r.r.Len():]
+
+	// decrypt with PBEWithSHAAnd3-KeyTripleDES-CBC
+	pw := pkcs12Password(password)
+	block, err := des.NewTripleDESCipher(pkcs12KDF(sha1.New, 64, pw, salt, iterations, pkcs12KDFKeyID, 24))
+	if err != nil {
+		return nil, err
+	}
+	iv := pkcs12KDF(sha1.New, 64, pw, salt, iterations, pkcs12KDFIVID, des.BlockSize)
+	plaintext, err := cbcDecrypt(block, iv, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := decodeBKSKey(newReader(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntegrityCheck, err)
+	}
+	return key, nil
+}
+
+// bksKey is an encoded key read from a BKS or UBER keystore.
+type bksKey struct {
+	keyType   byte
+	format    string
+	algorithm string
+	encoded   []byte
+}
+
+// decodeBKSKey reads an encoded key, which is either a PKCS#8 private key or a raw secret key.
+func decodeBKSKey(r *reader) (*bksKey, error) {
+	key := &bksKey{
+		keyType:   r.uint8(),
+		format:    r.str(),
+		algorithm: r.str(),
+		encoded:   r.bytes(),
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	switch {
+	case key.keyType == bksKeyTypePrivate && key.format == "PKCS#8":
+	case key.keyType == bksKeyTypeSecret && key.format == "RAW":
+	default:
+		return nil, fmt.Errorf("unsupported key type %d in format %q", key.keyType, key.format)
+	}
+	return key, nil
+}
+
+// setBKSKey sets the type & key of an entry from an encoded key, parsing private keys with parse.
+func setBKSKey(entry *Entry, key *bksKey, parse func([]byte) (any, error)) error {
+	if key.keyType == bksKeyTypeSecret {
+		entry.Type = EntryTypeSecretKey
+		entry.CertificateChain = nil
+		entry.SecretKey, entry.SecretKeyAlgorithm = key.encoded, key.algorithm
+		return nil
+	}
+
+	// the algorithm is also recorded in the PKCS#8 structure
+	privateKey, err := parse(key.encoded)
+	if err != nil {
+		return err
+	}
+	entry.Type = EntryTypePrivateKey
+	entry.PrivateKey = privateKey
+	return nil
+}
@@ -0,0 +1,62 @@
+package jks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+)
+
+// NewCertificateInfo returns metadata describing a certificate, e.g. for monitoring.
+func NewCertificateInfo(cert *x509.Certificate) *CertificateInfo {
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+
+	info := &CertificateInfo{
+		SHA1Fingerprint:   hex.EncodeToString(sha1Sum[:]),
+		SHA256Fingerprint: hex.EncodeToString(sha256Sum[:]),
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		SerialNumber:      cert.SerialNumber.Text(16),
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+		KeyAlgorithm:      cert.PublicKeyAlgorithm.String(),
+	}
+
+	// add subject alternative names, in the order printed by OpenSSL
+	for _, name := range cert.DNSNames {
+		info.SubjectAlternativeNames = append(info.SubjectAlternativeNames, "DNS:"+name)
+	}
+	for _, email := range cert.EmailAddresses {
+		info.SubjectAlternativeNames = append(info.SubjectAlternativeNames, "email:"+email)
+	}
+	for _, ip := range cert.IPAddresses {
+		info.SubjectAlternativeNames = append(info.SubjectAlternativeNames, "IP Address:"+ip.String())
+	}
+	for _, uri := range cert.URIs {
+		info.SubjectAlternativeNames = append(info.SubjectAlternativeNames, "URI:"+uri.String())
+	}
+
+	// add key algorithm, using Java algorithm names
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyAlgorithm, info.KeySize = "RSA", pub.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyAlgorithm, info.KeySize = "EC", pub.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		info.KeyAlgorithm, info.KeySize = "Ed25519", 256
+	}
+
+	return info
+}
+
+// CertificateInfo returns metadata describing the leaf certificate of the entry, or nil if it has no certificate.
+func (e *Entry) CertificateInfo() *CertificateInfo {
+	if len(e.CertificateChain) == 0 {
+		return nil
+	}
+	return NewCertificateInfo(e.CertificateChain[0])
+}
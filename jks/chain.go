@@ -0,0 +1,126 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+)
+
+// buildChain orders the certificate chain of leaf from an unordered pool of certificates, following issuers
+// until a self-issued certificate or a certificate with no issuer in the pool. Certificates in the pool that
+// are not part of the chain are dropped.
+// Returns ErrIncompleteChain if the pool holds certificates but none issued the leaf, or if the pool holds a
+// certificate named as the issuer of the last certificate in the chain that did not issue it.
+func buildChain(leaf *x509.Certificate, pool []*x509.Certificate) ([]*x509.Certificate, error) {
+	chain := []*x509.Certificate{leaf}
+	used := make([]bool, len(pool))
+
+	cur := leaf
+	for !isSelfIssued(cur) {
+		next := -1
+		for i, cert := range pool {
+			if !used[i] && issues(cert, cur) {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			break
+		}
+
+		used[next] = true
+		chain = append(chain, pool[next])
+		cur = pool[next]
+	}
+
+	// check the chain reaches a self-issued certificate, or a certificate whose issuer is not in the pool
+	if !isSelfIssued(cur) {
+		for _, cert := range pool {
+			if bytes.Equal(cert.RawSubject, cur.RawIssuer) {
+				return nil, fmt.Errorf("%w: issuer %q did not issue certificate %q", ErrIncompleteChain, cert.Subject, cur.Subject)
+			}
+		}
+		if len(chain) == 1 && len(pool) > 0 {
+			return nil, fmt.Errorf("%w: no issuer found for certificate %q", ErrIncompleteChain, cur.Subject)
+		}
+	}
+
+	return chain, nil
+}
+
+/*
+verifyChain verifies a certificate chain against trusted roots with x509.Certificate.Verify, checking signatures,
+CA basic constraints, path lengths & validity periods. The chain must also be the start of a verified path, so
+CA certificates that are out of order or not part of the path are rejected.
+
+Parameters:
+
+	`chain` - Certificate chain, starting with the leaf certificate
+	`roots` - Trusted root certificates, in X.509 PEM format
+*/
+func verifyChain(chain []*x509.Certificate, roots []byte) error {
+	rootPool := x509.NewCertPool()
+	blocks := decodeAllPEM(roots, "CERTIFICATE")
+	if len(blocks) == 0 {
+		return fmt.Errorf("error parsing trusted roots: %w", ErrEmptyBundle)
+	}
+	for i, bl := range blocks {
+		crt, err := x509.ParseCertificate(bl.Bytes)
+		if err != nil {
+			return fmt.Errorf("error parsing trusted root %d: %w", i, err)
+		}
+		rootPool.AddCert(crt)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, crt := range chain[1:] {
+		intermediates.AddCert(crt)
+	}
+
+	paths, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrChainVerify, err)
+	}
+
+	// check the chain is the start of a verified path
+	for _, path := range paths {
+		if isChainPrefix(chain, path) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: CA certificates are out of order, or are not part of the verified path", ErrChainVerify)
+}
+
+// isChainPrefix reports whether chain is the start of path.
+func isChainPrefix(chain, path []*x509.Certificate) bool {
+	if len(chain) > len(path) {
+		return false
+	}
+	for i, crt := range chain {
+		if !crt.Equal(path[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// issues reports whether issuer issued cert, matching the issuer name & any key identifiers, and verifying the
+// signature of cert.
+func issues(issuer, cert *x509.Certificate) bool {
+	if !bytes.Equal(issuer.RawSubject, cert.RawIssuer) {
+		return false
+	}
+	if len(cert.AuthorityKeyId) > 0 && len(issuer.SubjectKeyId) > 0 && !bytes.Equal(cert.AuthorityKeyId, issuer.SubjectKeyId) {
+		return false
+	}
+	return issuer.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// isSelfIssued reports whether a certificate's issuer & subject are the same, e.g. a root certificate authority.
+func isSelfIssued(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject)
+}
@@ -0,0 +1,20 @@
+/*
+Package jks builds & reads Java keystores in JKS, PKCS#12, JCEKS & BouncyCastle formats.
+
+Keystores are built with a KeystoreBuilder:
+
+	bld := jks.NewKeystoreBuilder()
+	bld.AddCert("server", certPEM, keyPEM, intermediatePEM)
+	bld.AddTrustedCert("ca", caPEM)
+	bld.SetPassword("changeit")
+	data, err := bld.Build()
+
+and read with Open, which detects the format automatically:
+
+	ks, err := jks.Open(data, "changeit")
+	for _, entry := range ks.PrivateKeyEntries() {
+		keyPEM, err := entry.PrivateKeyPEM()
+		chainPEM := entry.CertificateChainPEM()
+	}
+*/
+package jks
@@ -0,0 +1,136 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// Entry returns the entry with an alias, compared case-insensitively as by Java, or nil if there is no such entry.
+func (ks *Keystore) Entry(alias string) *Entry {
+	for _, entry := range ks.Entries {
+		if NormalizeAlias(entry.Alias) == NormalizeAlias(alias) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// PrivateKeyEntries returns the private key entries of the keystore, in alias order.
+func (ks *Keystore) PrivateKeyEntries() []*PrivateKeyEntry {
+	var entries []*PrivateKeyEntry
+	for _, entry := range ks.Entries {
+		if pkEntry, ok := entry.PrivateKeyEntry(); ok {
+			entries = append(entries, pkEntry)
+		}
+	}
+	return entries
+}
+
+// TrustedCertEntries returns the trusted certificate entries of the keystore, in alias order.
+func (ks *Keystore) TrustedCertEntries() []*TrustedCertEntry {
+	var entries []*TrustedCertEntry
+	for _, entry := range ks.Entries {
+		if tcEntry, ok := entry.TrustedCertEntry(); ok {
+			entries = append(entries, tcEntry)
+		}
+	}
+	return entries
+}
+
+// SecretKeyEntries returns the secret key entries of the keystore, in alias order.
+func (ks *Keystore) SecretKeyEntries() []*SecretKeyEntry {
+	var entries []*SecretKeyEntry
+	for _, entry := range ks.Entries {
+		if skEntry, ok := entry.SecretKeyEntry(); ok {
+			entries = append(entries, skEntry)
+		}
+	}
+	return entries
+}
+
+// PrivateKeyEntry returns the entry as a private key entry, if it is one.
+func (e *Entry) PrivateKeyEntry() (*PrivateKeyEntry, bool) {
+	if e.Type != EntryTypePrivateKey || e.PrivateKey == nil {
+		return nil, false
+	}
+	return &PrivateKeyEntry{
+		Alias:            e.Alias,
+		Created:          e.Created,
+		CertificateChain: e.CertificateChain,
+		PrivateKey:       e.PrivateKey,
+	}, true
+}
+
+// TrustedCertEntry returns the entry as a trusted certificate entry, if it is one.
+func (e *Entry) TrustedCertEntry() (*TrustedCertEntry, bool) {
+	if e.Type != EntryTypeTrustedCert || len(e.CertificateChain) == 0 {
+		return nil, false
+	}
+	return &TrustedCertEntry{
+		Alias:       e.Alias,
+		Created:     e.Created,
+		Certificate: e.CertificateChain[0],
+	}, true
+}
+
+// SecretKeyEntry returns the entry as a secret key entry, if it is one.
+func (e *Entry) SecretKeyEntry() (*SecretKeyEntry, bool) {
+	if e.Type != EntryTypeSecretKey || e.SecretKey == nil {
+		return nil, false
+	}
+	return &SecretKeyEntry{
+		Alias:     e.Alias,
+		Created:   e.Created,
+		Algorithm: e.SecretKeyAlgorithm,
+		Key:       e.SecretKey,
+	}, true
+}
+
+// PEM returns the entry in PEM format, as its private key followed by its certificate chain.
+func (e *Entry) PEM() ([]byte, error) {
+	key, err := e.PrivateKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Join(append([][]byte{key}, e.CertificateChainPEM()...), nil), nil
+}
+
+// entry returns the generic form of the entry.
+func (e *PrivateKeyEntry) entry() *Entry {
+	return &Entry{
+		Alias:            e.Alias,
+		Type:             EntryTypePrivateKey,
+		Created:          e.Created,
+		CertificateChain: e.CertificateChain,
+		PrivateKey:       e.PrivateKey,
+	}
+}
+
+// Certificate returns the leaf certificate of the entry, or nil if it has no certificate chain.
+func (e *PrivateKeyEntry) Certificate() *x509.Certificate {
+	if len(e.CertificateChain) == 0 {
+		return nil
+	}
+	return e.CertificateChain[0]
+}
+
+// CertificateChainPEM returns the certificate chain of the entry, in X.509 PEM format.
+func (e *PrivateKeyEntry) CertificateChainPEM() [][]byte {
+	return e.entry().CertificateChainPEM()
+}
+
+// PrivateKeyPEM returns the private key of the entry, in PKCS#8 PEM format.
+func (e *PrivateKeyEntry) PrivateKeyPEM() ([]byte, error) {
+	return e.entry().PrivateKeyPEM()
+}
+
+// PEM returns the entry in PEM format, as its private key followed by its certificate chain.
+func (e *PrivateKeyEntry) PEM() ([]byte, error) {
+	return e.entry().PEM()
+}
+
+// CertificatePEM returns the certificate of the entry, in X.509 PEM format.
+func (e *TrustedCertEntry) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: e.Certificate.Raw})
+}
@@ -1,8 +1,37 @@
 package jks
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrNoPassword   = errors.New("password is not set for store")
-	ErrInvalidAlias = errors.New("alias must not be an empty string")
+	ErrNoPassword       = errors.New("password is not set for store")
+	ErrInvalidAlias     = errors.New("invalid alias")
+	ErrDuplicateAlias   = errors.New("alias is used by more than one entry")
+	ErrEmptyBundle      = errors.New("no certificates found in bundle")
+	ErrInvalidStoreType = errors.New("unsupported store type")
+	ErrInvalidPassword  = errors.New("invalid password")
+	ErrUnknownFormat    = errors.New("unrecognised keystore format")
+	ErrIntegrityCheck   = errors.New("integrity check failed, the password may be incorrect")
+	ErrEncryptedKey     = errors.New("private key is encrypted, but no password is set for it")
+	ErrMultipleKeys     = errors.New("more than one private key found")
+	ErrInvalidSecretKey = errors.New("invalid secret key")
+	ErrNoSecretKeys     = errors.New("store type does not support secret keys")
+	ErrUnsupportedKey   = errors.New("unsupported private key algorithm")
+	ErrIncompleteChain  = errors.New("certificate chain cannot be completed")
+	ErrChainVerify      = errors.New("certificate chain verification failed")
+	ErrCertExpired      = errors.New("certificate has expired")
+	ErrCertNotYetValid  = errors.New("certificate is not yet valid")
+	ErrCertExpiring     = errors.New("certificate expires soon")
 )
+
+// KeyMismatchError is returned when a private key is not the counterpart of its certificate's public key.
+type KeyMismatchError struct {
+	// Alias of the key pair
+	Alias string
+}
+
+func (e *KeyMismatchError) Error() string {
+	return fmt.Sprintf("private key does not match the certificate public key for alias %q", e.Alias)
+}
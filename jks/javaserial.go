@@ -0,0 +1,484 @@
+package jks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Java object serialization stream constants, as used by JCEKS to store sealed secret keys.
+const (
+	javaStreamMagic   uint16 = 0xaced
+	javaStreamVersion uint16 = 5
+	javaBaseHandle    int32  = 0x7e0000
+
+	javaTCNull          byte = 0x70
+	javaTCReference     byte = 0x71
+	javaTCClassDesc     byte = 0x72
+	javaTCObject        byte = 0x73
+	javaTCString        byte = 0x74
+	javaTCArray         byte = 0x75
+	javaTCBlockData     byte = 0x77
+	javaTCEndBlockData  byte = 0x78
+	javaTCBlockDataLong byte = 0x7a
+	javaTCEnum          byte = 0x7e
+
+	javaSCWriteMethod  byte = 0x01
+	javaSCSerializable byte = 0x02
+
+	// javaMaxDepth limits the nesting of decoded objects.
+	javaMaxDepth = 16
+)
+
+type (
+	// javaClass describes a serializable Java class. Fields must be in serialization order, i.e. primitive fields
+	// followed by object fields, each sorted by name.
+	javaClass struct {
+		name   string
+		suid   int64
+		flags  byte
+		fields []javaField
+		super  *javaClass
+	}
+
+	// javaField is a field of a serializable Java class.
+	javaField struct {
+		// typeCode is the field type code, e.g. 'L' for objects or '[' for arrays.
+		typeCode byte
+		name     string
+		// className is the JVM type signature of object & array fields, e.g. "[B".
+		className string
+	}
+
+	// javaObject is a decoded Java object. Field values are strings, byte slices, *javaObject or nil.
+	// Enum constants are decoded to their name.
+	javaObject struct {
+		class  *javaClass
+		fields map[string]any
+	}
+)
+
+var (
+	javaByteArrayClass = &javaClass{
+		name:  "[B",
+		suid:  -5984413125824719648,
+		flags: javaSCSerializable,
+	}
+	javaSecretKeySpecClass = &javaClass{
+		name:  "javax.crypto.spec.SecretKeySpec",
+		suid:  6577238317307289933,
+		flags: javaSCSerializable,
+		fields: []javaField{
+			{typeCode: 'L', name: "algorithm", className: "Ljava/lang/String;"},
+			{typeCode: '[', name: "key", className: "[B"},
+		},
+	}
+	javaSealedObjectClass = &javaClass{
+		name:  "javax.crypto.SealedObject",
+		suid:  4482838265551344752,
+		flags: javaSCSerializable,
+		fields: []javaField{
+			{typeCode: '[', name: "encodedParams", className: "[B"},
+			{typeCode: '[', name: "encryptedContent", className: "[B"},
+			{typeCode: 'L', name: "paramsAlg", className: "Ljava/lang/String;"},
+			{typeCode: 'L', name: "sealAlg", className: "Ljava/lang/String;"},
+		},
+	}
+	javaSealedObjectForKeyProtectorClass = &javaClass{
+		name:  "com.sun.crypto.provider.SealedObjectForKeyProtector",
+		suid:  -3650226485480866989,
+		flags: javaSCSerializable,
+		super: javaSealedObjectClass,
+	}
+)
+
+// javaEncoder writes a Java object serialization stream. Strings & class descriptors are written once, with
+// back references for repeats, as ObjectOutputStream does.
+type javaEncoder struct {
+	buf     bytes.Buffer
+	handles map[any]int32
+	next    int32
+}
+
+// newJavaEncoder creates an encoder & writes the stream header.
+func newJavaEncoder() *javaEncoder {
+	e := &javaEncoder{handles: make(map[any]int32), next: javaBaseHandle}
+	_ = binary.Write(&e.buf, binary.BigEndian, javaStreamMagic)
+	_ = binary.Write(&e.buf, binary.BigEndian, javaStreamVersion)
+	return e
+}
+
+// assign assigns the next handle to a value.
+func (e *javaEncoder) assign(val any) {
+	if val != nil {
+		e.handles[val] = e.next
+	}
+	e.next++
+}
+
+// writeReference writes a back reference to a value if it has been written before.
+func (e *javaEncoder) writeReference(val any) bool {
+	handle, ok := e.handles[val]
+	if ok {
+		e.buf.WriteByte(javaTCReference)
+		_ = binary.Write(&e.buf, binary.BigEndian, handle)
+	}
+	return ok
+}
+
+// writeUTF writes a string prefixed with its 16-bit length.
+func (e *javaEncoder) writeUTF(s string) error {
+	return writeStr(&e.buf, s)
+}
+
+// writeString writes a string object.
+func (e *javaEncoder) writeString(s string) error {
+	if e.writeReference(s) {
+		return nil
+	}
+	e.buf.WriteByte(javaTCString)
+	e.assign(s)
+	return e.writeUTF(s)
+}
+
+// writeClassDesc writes a class descriptor & its superclass descriptors.
+func (e *javaEncoder) writeClassDesc(c *javaClass) error {
+	if c == nil {
+		e.buf.WriteByte(javaTCNull)
+		return nil
+	}
+	if e.writeReference(c) {
+		return nil
+	}
+
+	e.buf.WriteByte(javaTCClassDesc)
+	e.assign(c)
+	if err := e.writeUTF(c.name); err != nil {
+		return err
+	}
+	_ = binary.Write(&e.buf, binary.BigEndian, c.suid)
+	e.buf.WriteByte(c.flags)
+	_ = binary.Write(&e.buf, binary.BigEndian, uint16(len(c.fields)))
+	for _, f := range c.fields {
+		e.buf.WriteByte(f.typeCode)
+		if err := e.writeUTF(f.name); err != nil {
+			return err
+		}
+		if f.className != "" {
+			if err := e.writeString(f.className); err != nil {
+				return err
+			}
+		}
+	}
+	e.buf.WriteByte(javaTCEndBlockData)
+
+	return e.writeClassDesc(c.super)
+}
+
+// writeByteArray writes a byte array object.
+func (e *javaEncoder) writeByteArray(b []byte) error {
+	e.buf.WriteByte(javaTCArray)
+	if err := e.writeClassDesc(javaByteArrayClass); err != nil {
+		return err
+	}
+	e.assign(nil)
+	writeBytes(&e.buf, b)
+	return nil
+}
+
+// writeObject writes an object of a class with only string & byte array fields. Values are given for the fields
+// of each class in the hierarchy, starting with the topmost superclass.
+func (e *javaEncoder) writeObject(c *javaClass, values ...any) error {
+	e.buf.WriteByte(javaTCObject)
+	if err := e.writeClassDesc(c); err != nil {
+		return err
+	}
+	e.assign(nil)
+
+	for _, val := range values {
+		var err error
+		switch val := val.(type) {
+		case string:
+			err = e.writeString(val)
+		case []byte:
+			err = e.writeByteArray(val)
+		default:
+			err = fmt.Errorf("unsupported field value %T", val)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// javaDecoder reads a Java object serialization stream. Only the subset needed for sealed secret keys is
+// supported: objects with primitive, string, byte array, enum & object fields.
+type javaDecoder struct {
+	r       *reader
+	handles []any
+	depth   int
+}
+
+// readJavaObject reads a Java object serialization stream containing a single object from r, leaving r after
+// the end of the object.
+func readJavaObject(r *reader) (*javaObject, error) {
+	header := r.read(4)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if binary.BigEndian.Uint16(header) != javaStreamMagic || binary.BigEndian.Uint16(header[2:]) != javaStreamVersion {
+		return nil, errors.New("invalid Java serialization stream header")
+	}
+
+	d := &javaDecoder{r: r}
+	val, err := d.readContent()
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := val.(*javaObject)
+	if !ok {
+		return nil, fmt.Errorf("expected serialized object, found %T", val)
+	}
+	return obj, nil
+}
+
+// readContent reads a single content element.
+func (d *javaDecoder) readContent() (any, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > javaMaxDepth {
+		return nil, errors.New("serialized object nested too deeply")
+	}
+
+	tag := d.r.uint8()
+	if d.r.err != nil {
+		return nil, d.r.err
+	}
+
+	switch tag {
+	case javaTCNull:
+		return nil, nil
+	case javaTCReference:
+		idx := int64(int32(d.r.uint32())) - int64(javaBaseHandle)
+		if d.r.err != nil {
+			return nil, d.r.err
+		}
+		if idx < 0 || idx >= int64(len(d.handles)) {
+			return nil, fmt.Errorf("invalid handle %d", idx)
+		}
+		return d.handles[idx], nil
+	case javaTCString:
+		s := d.r.str()
+		d.handles = append(d.handles, s)
+		return s, d.r.err
+	case javaTCClassDesc:
+		return d.readClassDesc()
+	case javaTCArray:
+		return d.readArray()
+	case javaTCEnum:
+		return d.readEnum()
+	case javaTCObject:
+		return d.readObject()
+	default:
+		return nil, fmt.Errorf("unsupported serialization tag 0x%02x", tag)
+	}
+}
+
+// readClassDesc reads a class descriptor, after its tag.
+func (d *javaDecoder) readClassDesc() (*javaClass, error) {
+	c := &javaClass{name: d.r.str(), suid: int64(d.r.uint64())}
+	d.handles = append(d.handles, c)
+	c.flags = d.r.uint8()
+	count := d.r.read(2)
+	if d.r.err != nil {
+		return nil, d.r.err
+	}
+
+	for i := 0; i < int(binary.BigEndian.Uint16(count)); i++ {
+		f := javaField{typeCode: d.r.uint8(), name: d.r.str()}
+		if d.r.err != nil {
+			return nil, d.r.err
+		}
+		if f.typeCode == 'L' || f.typeCode == '[' {
+			className, err := d.readContent()
+			if err != nil {
+				return nil, err
+			}
+			s, ok := className.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid type of field %q", f.name)
+			}
+			f.className = s
+		}
+		c.fields = append(c.fields, f)
+	}
+
+	if err := d.skipAnnotation(); err != nil {
+		return nil, err
+	}
+	super, err := d.readClassDescRef()
+	if err != nil {
+		return nil, err
+	}
+	c.super = super
+	return c, nil
+}
+
+// readClassDescRef reads a class descriptor, a reference to one, or null.
+func (d *javaDecoder) readClassDescRef() (*javaClass, error) {
+	val, err := d.readContent()
+	if err != nil || val == nil {
+		return nil, err
+	}
+	c, ok := val.(*javaClass)
+	if !ok {
+		return nil, fmt.Errorf("expected class descriptor, found %T", val)
+	}
+	return c, nil
+}
+
+// readArray reads an array, after its tag. Only byte arrays are supported.
+func (d *javaDecoder) readArray() ([]byte, error) {
+	c, err := d.readClassDescRef()
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.name != "[B" {
+		return nil, errors.New("unsupported array type")
+	}
+	idx := len(d.handles)
+	d.handles = append(d.handles, nil)
+	b := d.r.bytes()
+	d.handles[idx] = b
+	return b, d.r.err
+}
+
+// readEnum reads an enum constant, after its tag, returning its name.
+func (d *javaDecoder) readEnum() (string, error) {
+	if _, err := d.readClassDescRef(); err != nil {
+		return "", err
+	}
+	idx := len(d.handles)
+	d.handles = append(d.handles, nil)
+	val, err := d.readContent()
+	if err != nil {
+		return "", err
+	}
+	name, ok := val.(string)
+	if !ok {
+		return "", errors.New("invalid enum constant name")
+	}
+	d.handles[idx] = name
+	return name, nil
+}
+
+// readObject reads an object, after its tag.
+func (d *javaDecoder) readObject() (*javaObject, error) {
+	c, err := d.readClassDescRef()
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, errors.New("object has no class descriptor")
+	}
+	obj := &javaObject{class: c, fields: make(map[string]any)}
+	d.handles = append(d.handles, obj)
+
+	// field values are written for each class in the hierarchy, starting with the topmost superclass
+	var hierarchy []*javaClass
+	for cur := c; cur != nil; cur = cur.super {
+		if len(hierarchy) >= javaMaxDepth {
+			return nil, errors.New("class hierarchy too deep")
+		}
+		hierarchy = append([]*javaClass{cur}, hierarchy...)
+	}
+	for _, cur := range hierarchy {
+		if cur.flags&javaSCSerializable == 0 {
+			return nil, fmt.Errorf("unsupported class %q", cur.name)
+		}
+		for _, f := range cur.fields {
+			val, err := d.readField(f)
+			if err != nil {
+				return nil, fmt.Errorf("error reading field %q of %q: %w", f.name, cur.name, err)
+			}
+			obj.fields[f.name] = val
+		}
+		if cur.flags&javaSCWriteMethod != 0 {
+			if err := d.skipAnnotation(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return obj, nil
+}
+
+// readField reads a field value. Primitive values are skipped.
+func (d *javaDecoder) readField(f javaField) (any, error) {
+	var size int
+	switch f.typeCode {
+	case 'L', '[':
+		return d.readContent()
+	case 'B', 'Z':
+		size = 1
+	case 'C', 'S':
+		size = 2
+	case 'F', 'I':
+		size = 4
+	case 'D', 'J':
+		size = 8
+	default:
+		return nil, fmt.Errorf("unsupported field type %q", f.typeCode)
+	}
+	d.r.read(size)
+	return nil, d.r.err
+}
+
+// skipAnnotation skips block data & objects up to the end of a class annotation.
+func (d *javaDecoder) skipAnnotation() error {
+	for {
+		tag := d.r.uint8()
+		if d.r.err != nil {
+			return d.r.err
+		}
+		switch tag {
+		case javaTCEndBlockData:
+			return nil
+		case javaTCBlockData:
+			d.r.read(int(d.r.uint8()))
+		case javaTCBlockDataLong:
+			d.r.bytes()
+		default:
+			// unread the tag & skip the object
+			if err := d.r.r.UnreadByte(); err != nil {
+				return err
+			}
+			if _, err := d.readContent(); err != nil {
+				return err
+			}
+		}
+		if d.r.err != nil {
+			return d.r.err
+		}
+	}
+}
+
+// stringField returns a string field of an object.
+func (o *javaObject) stringField(name string) (string, error) {
+	s, ok := o.fields[name].(string)
+	if !ok {
+		return "", fmt.Errorf("missing string field %q of %q", name, o.class.name)
+	}
+	return s, nil
+}
+
+// bytesField returns a byte array field of an object.
+func (o *javaObject) bytesField(name string) ([]byte, error) {
+	b, ok := o.fields[name].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("missing byte array field %q of %q", name, o.class.name)
+	}
+	return b, nil
+}
@@ -1,54 +1,141 @@
 package jks
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"crypto/x509"
+	"encoding/asn1"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/lwithers/minijks/jks"
 )
 
-// certChain generates a chain of certificates, starting with the server cert.
-func (k keyPair) certChain() ([]*x509.Certificate, error) {
-	// prepend server cert to CA certs
-	pemCerts := append([][]byte{k.cert}, k.caCerts...)
+// unsupportedKeyAlgorithms maps the PKCS#8 algorithm OIDs of private keys that can't be stored in a keystore
+// to their names, as the Go standard library can't parse them, or Java keystores can't hold them.
+var unsupportedKeyAlgorithms = map[string]string{
+	"1.2.840.10040.4.1":     "DSA",
+	"1.2.840.10046.2.1":     "Diffie-Hellman",
+	"1.2.840.113549.1.3.1":  "Diffie-Hellman",
+	"1.2.840.113549.1.1.10": "RSASSA-PSS",
+	"1.3.101.110":           "X25519",
+	"1.3.101.111":           "X448",
+	"1.3.101.113":           "Ed448",
+}
 
-	certs := make([]*x509.Certificate, len(pemCerts))
+// certChain generates a chain of certificates, starting with the server cert.
+// Any further certificates after the server cert, e.g. in a full chain file, precede the CA certs, which may each
+// be a bundle. If build is set, the CA certs are ordered into a chain with buildChain, rather than used in the
+// given order.
+func (k keyPair) certChain(build bool) ([]*x509.Certificate, error) {
+	certs, err := ParseCertificatesPEM(k.cert)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate: %w", err)
+	}
 
-	for i, pemCert := range pemCerts {
-		// parse cert & add to slice
-		crt, err := parseCertPEM(pemCert)
+	for i, caCert := range k.caCerts {
+		crts, err := ParseCertificatesPEM(caCert)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing certificate %d: %w", i, err)
+			return nil, fmt.Errorf("error parsing CA certificate %d: %w", i, err)
 		}
-		certs[i] = crt
+		certs = append(certs, crts...)
 	}
 
+	if build {
+		return buildChain(certs[0], certs[1:])
+	}
 	return certs, nil
 }
 
-// privKey decodes the private key to a private key format.
+// privKey decodes the private key to a private key format, decrypting it if it is encrypted.
 func (k keyPair) privKey() (any, error) {
 	// parse key from PEM
-	pemKey, err := decodePEM(k.key)
+	pemKey, err := decodeKeyPEM(k.key)
 	if err != nil {
 		return nil, err
 	}
 
+	// decrypt key
+	der := pemKey.Bytes
+	switch {
+	case pemKey.Type == "ENCRYPTED PRIVATE KEY":
+		if k.keyPEMPassword == "" {
+			return nil, ErrEncryptedKey
+		}
+		return decryptPKCS8(der, k.keyPEMPassword)
+	case x509.IsEncryptedPEMBlock(pemKey): //nolint:staticcheck // legacy encryption is insecure, but still widely used
+		if k.keyPEMPassword == "" {
+			return nil, ErrEncryptedKey
+		}
+		der, err = x509.DecryptPEMBlock(pemKey, []byte(k.keyPEMPassword)) //nolint:staticcheck // as above
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, ErrIntegrityCheck
+		} else if err != nil {
+			return nil, fmt.Errorf("error decrypting private key: %w", err)
+		}
+	}
+
 	// decode key
 	switch typ := pemKey.Type; typ {
 	case "PRIVATE KEY":
-		return x509.ParsePKCS8PrivateKey(pemKey.Bytes)
+		return parsePKCS8Key(der)
 	case "RSA PRIVATE KEY":
-		return x509.ParsePKCS1PrivateKey(pemKey.Bytes)
+		return x509.ParsePKCS1PrivateKey(der)
 	case "EC PRIVATE KEY":
-		return x509.ParseECPrivateKey(pemKey.Bytes)
+		return x509.ParseECPrivateKey(der)
 	default:
 		return nil, fmt.Errorf("unknown key type: %s", typ)
 	}
 }
 
-// toJKSKeypair converts keyPair to a *jks.Keypair.
-func (k keyPair) toJKSKeypair(alias string) (*jks.Keypair, error) {
+// parsePKCS8Key parses an unencrypted PKCS#8 private key, returning ErrUnsupportedKey with the algorithm name
+// for known algorithms that can't be stored in a keystore.
+func parsePKCS8Key(der []byte) (any, error) {
+	var keyInfo jks.PrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &keyInfo); err == nil {
+		if name, ok := unsupportedKeyAlgorithms[keyInfo.Algo.Algorithm.String()]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, name)
+		}
+	}
+	return x509.ParsePKCS8PrivateKey(der)
+}
+
+// checkKeyAlgorithm returns ErrUnsupportedKey if a private key can't be used by Java. Java 16 & later only support
+// the NIST P-256, P-384 & P-521 curves for ECDSA keys, and Java 15 & later support Ed25519 keys.
+func checkKeyAlgorithm(key any) error {
+	switch key := key.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+		return nil
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+			return nil
+		}
+		return fmt.Errorf("%w: ECDSA with curve %s", ErrUnsupportedKey, key.Curve.Params().Name)
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+}
+
+// decryptPKCS8 decrypts an encrypted PKCS#8 private key, encrypted with PBES2 or PKCS#12 password based encryption.
+func decryptPKCS8(der []byte, password string) (any, error) {
+	var keyInfo jks.EncryptedPrivateKeyInfo
+	if err := unmarshalDER(der, &keyInfo); err != nil {
+		return nil, fmt.Errorf("error unmarshalling encrypted private key: %w", err)
+	}
+	plaintext, err := pbeDecrypt(keyInfo.Algo, keyInfo.EncryptedData, password)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting private key: %w", err)
+	}
+	return parsePKCS8(plaintext)
+}
+
+// toJKSKeypair converts keyPair to a *jks.Keypair, building the certificate chain if build is set.
+func (k keyPair) toJKSKeypair(alias string, build bool) (*jks.Keypair, error) {
 	// get private key
 	privKey, err := k.privKey()
 	if err != nil {
@@ -56,7 +143,7 @@ func (k keyPair) toJKSKeypair(alias string) (*jks.Keypair, error) {
 	}
 
 	// get cert chain
-	certs, err := k.certChain()
+	certs, err := k.certChain(build)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing certificate chain: %w", err)
 	}
@@ -77,3 +164,57 @@ func (k keyPair) toJKSKeypair(alias string) (*jks.Keypair, error) {
 
 	return jksKp, nil
 }
+
+// checkValidity returns an error if any certificate in the chain is expired or not yet valid at now, and warnings
+// for certificates that expire within minValidity of now.
+func (k keyPair) checkValidity(alias string, build bool, now time.Time, minValidity time.Duration) ([]error, error) {
+	certs, err := k.certChain(build)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate chain for alias %q: %w", alias, err)
+	}
+
+	var warnings []error
+	for _, crt := range certs {
+		switch {
+		case now.Before(crt.NotBefore):
+			return nil, fmt.Errorf("%w: certificate %q for alias %q is valid from %s", ErrCertNotYetValid, crt.Subject, alias, crt.NotBefore.Format(time.RFC3339))
+		case now.After(crt.NotAfter):
+			return nil, fmt.Errorf("%w: certificate %q for alias %q expired at %s", ErrCertExpired, crt.Subject, alias, crt.NotAfter.Format(time.RFC3339))
+		case crt.NotAfter.Sub(now) < minValidity:
+			warnings = append(warnings, fmt.Errorf("%w: certificate %q for alias %q expires at %s", ErrCertExpiring, crt.Subject, alias, crt.NotAfter.Format(time.RFC3339)))
+		}
+	}
+
+	return warnings, nil
+}
+
+// checkKey returns ErrUnsupportedKey if the private key can't be stored in a keystore, or a *KeyMismatchError if
+// it is not the counterpart of the leaf certificate.
+func (k keyPair) checkKey(alias string) error {
+	privKey, err := k.privKey()
+	if err != nil {
+		return fmt.Errorf("error parsing private key for alias %q: %w", alias, err)
+	}
+	if err := checkKeyAlgorithm(privKey); err != nil {
+		return fmt.Errorf("invalid private key for alias %q: %w", alias, err)
+	}
+	certs, err := ParseCertificatesPEM(k.cert)
+	if err != nil {
+		return fmt.Errorf("error parsing certificate for alias %q: %w", alias, err)
+	}
+	cert := certs[0]
+	if !publicKeyMatches(privKey, cert) {
+		return &KeyMismatchError{Alias: alias}
+	}
+	return nil
+}
+
+// publicKeyMatches reports whether a private key is the counterpart of a certificate's public key.
+func publicKeyMatches(key any, cert *x509.Certificate) bool {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return false
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(cert.PublicKey)
+}
@@ -0,0 +1,123 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/md5"
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/lwithers/minijks/jks"
+)
+
+// jksKeyProtectorSaltLen is the salt length used by the JKS key protector.
+const jksKeyProtectorSaltLen = sha1.Size
+
+// jksProtectKey encrypts a PKCS#8 private key with Sun's proprietary JKS key protector.
+// The result is salt || (plaintext XOR SHA-1 keystream) || SHA-1(password || plaintext).
+func jksProtectKey(plaintext []byte, password string, salt []byte) []byte {
+	passwd := jks.PasswordUTF16(password)
+
+	// generate keystream by chaining SHA-1 over password & previous digest, starting from the salt
+	out := make([]byte, 0, len(salt)+len(plaintext)+sha1.Size)
+	out = append(out, salt...)
+	digest := salt
+	for i := 0; i < len(plaintext); i++ {
+		if i%sha1.Size == 0 {
+			md := sha1.New()
+			md.Write(passwd)
+			md.Write(digest)
+			digest = md.Sum(nil)
+		}
+		out = append(out, plaintext[i]^digest[i%sha1.Size])
+	}
+
+	// append integrity check
+	md := sha1.New()
+	md.Write(passwd)
+	md.Write(plaintext)
+
+	return md.Sum(out)
+}
+
+const (
+	// jceksKeyProtectorSaltLen is the salt length used by the JCEKS key protector.
+	jceksKeyProtectorSaltLen = 8
+	// jceksKeyProtectorIterations is the iteration count used by the JCEKS key protector, matching Java's default.
+	jceksKeyProtectorIterations = 200000
+)
+
+// pbeParameter is the PKCS#5 PBEParameter structure.
+type pbeParameter struct {
+	Salt           []byte
+	IterationCount int
+}
+
+// jceksProtectKey encrypts a PKCS#8 private key with Sun's PBEWithMD5AndTripleDES key protector, as used by JCEKS.
+func jceksProtectKey(plaintext []byte, password string, salt []byte, iterations int) ([]byte, error) {
+	key, iv := pbeWithMD5AndTripleDESKey(password, salt, iterations)
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := pkcs7Pad(plaintext, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+
+	return ciphertext, nil
+}
+
+// jceksUnprotectKey decrypts a PKCS#8 private key protected with Sun's PBEWithMD5AndTripleDES key protector.
+func jceksUnprotectKey(ciphertext []byte, password string, salt []byte, iterations int) ([]byte, error) {
+	if len(salt) != jceksKeyProtectorSaltLen {
+		return nil, fmt.Errorf("invalid salt length %d", len(salt))
+	}
+	if iterations < 1 || iterations > maxPBEIterations {
+		return nil, fmt.Errorf("invalid iteration count %d", iterations)
+	}
+	key, iv := pbeWithMD5AndTripleDESKey(password, salt, iterations)
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cbcDecrypt(block, iv, ciphertext)
+}
+
+// pbeWithMD5AndTripleDESKey derives the triple DES key & IV for Sun's proprietary PBEWithMD5AndTripleDES algorithm.
+// Each half of the salt is hashed with the password to produce 16 bytes of output, giving a 24 byte key & 8 byte IV.
+func pbeWithMD5AndTripleDESKey(password string, salt []byte, iterations int) ([]byte, []byte) {
+	salt = append([]byte(nil), salt...)
+
+	// if the salt halves are equal, Java reverses the first half
+	if bytes.Equal(salt[:4], salt[4:]) {
+		for i := 0; i < 2; i++ {
+			salt[i], salt[3-i] = salt[3-i], salt[i]
+		}
+	}
+
+	out := make([]byte, 0, 2*md5.Size)
+	for i := 0; i < 2; i++ {
+		digest := salt[i*4 : (i+1)*4]
+		for j := 0; j < iterations; j++ {
+			md := md5.New()
+			md.Write(digest)
+			md.Write([]byte(password))
+			digest = md.Sum(nil)
+		}
+		out = append(out, digest...)
+	}
+
+	return out[:24], out[24:]
+}
+
+// isPrintableASCII reports whether s only contains printable ASCII characters, as required for JCEKS key passwords.
+func isPrintableASCII(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,12 @@
 package jks
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/lwithers/minijks/jks"
 )
@@ -9,13 +14,17 @@ import (
 // NewKeystoreBuilder creates a new KeyStoreBuilder.
 func NewKeystoreBuilder() *KeystoreBuilder {
 	return &KeystoreBuilder{
-		keyPairs: make(map[string]keyPair),
+		keyPairs:     make(map[string]keyPair),
+		trustedCerts: make(map[string]trustedCert),
+		secretKeys:   make(map[string]secretKey),
+		storeType:    StoreTypeJKS,
 	}
 }
 
 /*
 AddCert adds a certificate and private key to the key store.
-If an alias is reused, this overwrites the previous cert.
+If an alias is reused, this overwrites the previous cert. Aliases are case-insensitive, so Build rejects
+aliases that differ only in case, except that this replaces entries added by AddKeystore whatever their case.
 
 Parameters:
 
@@ -25,6 +34,7 @@ Parameters:
 	`caCerts` - Optional intermediate certificate authorities to add to keypair, in X.509 PEM format
 */
 func (k *KeystoreBuilder) AddCert(alias string, cert []byte, key []byte, caCerts ...[]byte) {
+	k.removeImported(alias)
 	k.keyPairs[alias] = keyPair{
 		key:     key,
 		cert:    cert,
@@ -32,11 +42,349 @@ func (k *KeystoreBuilder) AddCert(alias string, cert []byte, key []byte, caCerts
 	}
 }
 
+/*
+SetPrivateKeyPassword sets the password used to decrypt an encrypted private key, for a key pair added with AddCert.
+Encrypted PKCS#8 keys ("ENCRYPTED PRIVATE KEY" blocks) & legacy OpenSSL keys with a DEK-Info header are supported.
+This has no effect if no key pair has been added with the alias.
+
+Parameters:
+
+	`alias`    - Alias for cert/key pair
+	`password` - Password for the private key PEM
+*/
+func (k *KeystoreBuilder) SetPrivateKeyPassword(alias string, password string) {
+	if kp, ok := k.keyPairs[alias]; ok {
+		kp.keyPEMPassword = password
+		k.keyPairs[alias] = kp
+	}
+}
+
+/*
+SetKeyPassword sets the password protecting a private or secret key in the keystore, for a key pair added with
+AddCert or a secret key added with AddSecretKey. Keys are protected with the store password unless a key password
+is set. This has no effect if no key pair or secret key has been added with the alias.
+
+Parameters:
+
+	`alias`    - Alias for cert/key pair or secret key
+	`password` - Password for the key entry
+*/
+func (k *KeystoreBuilder) SetKeyPassword(alias string, password string) {
+	if kp, ok := k.keyPairs[alias]; ok {
+		kp.keyPassword = password
+		k.keyPairs[alias] = kp
+	}
+	if sk, ok := k.secretKeys[alias]; ok {
+		sk.keyPassword = password
+		k.secretKeys[alias] = sk
+	}
+}
+
+/*
+SetTrustedRoots sets the trusted root certificates to verify the certificate chain of a key pair against, for a key
+pair added with AddCert. Build returns an error wrapping ErrChainVerify if the chain does not verify, see VerifyChain.
+This has no effect if no key pair has been added with the alias.
+
+Parameters:
+
+	`alias` - Alias for cert/key pair
+	`roots` - Concatenated root certificates, in X.509 PEM format
+*/
+func (k *KeystoreBuilder) SetTrustedRoots(alias string, roots []byte) {
+	if kp, ok := k.keyPairs[alias]; ok {
+		kp.trustedRoots = roots
+		k.keyPairs[alias] = kp
+	}
+}
+
+/*
+VerifyChain verifies the certificate chain of a key pair against trusted root certificates, without requiring the
+roots for Build. Signatures, CA basic constraints, path lengths & validity periods are checked, and the chain must
+be the start of a verified path, so CA certificates that are out of order or not part of the path are rejected.
+The chain is built as it would be by Build, so it is ordered first if SetBuildChains is enabled.
+
+Returns an error wrapping ErrChainVerify if verification fails.
+
+Parameters:
+
+	`alias` - Alias for cert/key pair
+	`roots` - Concatenated root certificates, in X.509 PEM format
+*/
+func (k *KeystoreBuilder) VerifyChain(alias string, roots []byte) error {
+	kp, ok := k.keyPairs[alias]
+	if !ok {
+		return fmt.Errorf("no key pair found for alias %q", alias)
+	}
+	chain, err := kp.certChain(k.buildChains)
+	if err != nil {
+		return fmt.Errorf("error parsing certificate chain for alias %q: %w", alias, err)
+	}
+	if err := verifyChain(chain, roots); err != nil {
+		return fmt.Errorf("alias %q: %w", alias, err)
+	}
+	return nil
+}
+
+/*
+AddTrustedCert adds a trusted certificate entry to the key store, e.g. a certificate authority for a truststore.
+If an alias is reused, this overwrites the previous cert. Aliases are case-insensitive, so Build rejects
+aliases that differ only in case, except that this replaces entries added by AddKeystore whatever their case.
+
+Parameters:
+
+	`alias` - Alias for certificate
+	`cert`  - Certificate, in X.509 PEM format
+*/
+func (k *KeystoreBuilder) AddTrustedCert(alias string, cert []byte) {
+	k.removeImported(alias)
+	k.trustedCerts[alias] = trustedCert{cert: cert}
+}
+
+/*
+AddSecretKey adds a symmetric secret key to the key store, as created by keytool -genseckey.
+If an alias is reused, this overwrites the previous key, and entries added by AddKeystore are replaced as for
+AddCert. Secret keys can be stored in the SecretKeyStoreTypes, for other store types Build returns ErrNoSecretKeys.
+
+Parameters:
+
+	`alias`     - Alias for secret key
+	`algorithm` - Java algorithm name, one of AES, DESede, HmacSHA1, HmacSHA224, HmacSHA256, HmacSHA384 or HmacSHA512
+	`key`       - Raw key material
+*/
+func (k *KeystoreBuilder) AddSecretKey(alias string, algorithm string, key []byte) {
+	k.removeImported(alias)
+	k.secretKeys[alias] = secretKey{
+		algorithm: algorithm,
+		key:       key,
+	}
+}
+
+/*
+AddTrustedCertBundle adds every certificate in a PEM bundle as a trusted certificate entry, skipping any
+duplicate certificates & non-certificate PEM blocks. Aliases are generated from the subject common name & the
+SHA-256 fingerprint of each certificate.
+
+Returns the generated aliases, in bundle order.
+
+Parameters:
+
+	`bundle` - Concatenated certificates, in X.509 PEM format
+*/
+func (k *KeystoreBuilder) AddTrustedCertBundle(bundle []byte) ([]string, error) {
+	blocks := decodeAllPEM(bundle, "CERTIFICATE")
+	if len(blocks) == 0 {
+		return nil, ErrEmptyBundle
+	}
+
+	aliases := make([]string, 0, len(blocks))
+	seen := make(map[[sha256.Size]byte]bool, len(blocks))
+
+	for i, bl := range blocks {
+		crt, err := x509.ParseCertificate(bl.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate %d in bundle: %w", i, err)
+		}
+
+		// skip duplicates
+		fingerprint := sha256.Sum256(crt.Raw)
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+
+		// add certificate with generated alias
+		alias := generateAlias(crt)
+		k.AddTrustedCert(alias, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw}))
+		aliases = append(aliases, alias)
+	}
+
+	return aliases, nil
+}
+
+/*
+AddKeystore adds every entry of an existing keystore to the key store, e.g. to extend the JDK cacerts truststore.
+Entries are added as key pairs, trusted certificates & secret keys. Entries added later with the same alias replace
+them & RemoveEntry removes them, ignoring case. The keystore may be in any format supported by Open. Key pairs added
+from the keystore are not checked for expired or soon to expire certificates, as they are not under the caller's
+control.
+
+Parameters:
+
+	`data`     - Keystore, in any supported format
+	`password` - Password for keystore & private keys
+*/
+func (k *KeystoreBuilder) AddKeystore(data []byte, password string) error {
+	ks, err := Open(data, password)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range ks.Entries {
+		chain := entry.CertificateChainPEM()
+
+		switch entry.Type {
+		case EntryTypePrivateKey:
+			key, err := entry.PrivateKeyPEM()
+			if err != nil {
+				return fmt.Errorf("error encoding private key %q: %w", entry.Alias, err)
+			}
+			if len(chain) == 0 {
+				return fmt.Errorf("private key %q has no certificate", entry.Alias)
+			}
+			k.AddCert(entry.Alias, chain[0], key, chain[1:]...)
+			kp := k.keyPairs[entry.Alias]
+			kp.imported = true
+			k.keyPairs[entry.Alias] = kp
+		case EntryTypeTrustedCert:
+			k.AddTrustedCert(entry.Alias, chain[0])
+			tc := k.trustedCerts[entry.Alias]
+			tc.imported = true
+			k.trustedCerts[entry.Alias] = tc
+		case EntryTypeSecretKey:
+			k.AddSecretKey(entry.Alias, entry.SecretKeyAlgorithm, entry.SecretKey)
+			sk := k.secretKeys[entry.Alias]
+			sk.imported = true
+			k.secretKeys[entry.Alias] = sk
+		}
+	}
+
+	return nil
+}
+
+/*
+CheckSecretKey verifies that a secret key has a supported algorithm & a valid key length, as Build does for every
+secret key. Returns an error wrapping ErrInvalidSecretKey otherwise.
+
+Parameters:
+
+	`alias`     - Alias for secret key, used in errors
+	`algorithm` - Java algorithm name
+	`key`       - Raw key material
+*/
+func CheckSecretKey(alias string, algorithm string, key []byte) error {
+	return secretKey{algorithm: algorithm, key: key}.checkSecretKey(alias)
+}
+
+/*
+CheckKeyPair verifies that a private key is supported & is the counterpart of a certificate's public key, as
+Build does for every key pair. Returns a *KeyMismatchError if they do not match.
+
+Parameters:
+
+	`alias`          - Alias for cert/key pair, used in errors
+	`cert`           - Certificate, in X.509 PEM format
+	`key`            - Private key, in PEM format
+	`keyPEMPassword` - Password for an encrypted private key, or an empty string if the key is not encrypted
+*/
+func CheckKeyPair(alias string, cert []byte, key []byte, keyPEMPassword string) error {
+	return keyPair{cert: cert, key: key, keyPEMPassword: keyPEMPassword}.checkKey(alias)
+}
+
+// RemoveEntry removes the key pair, trusted certificate or secret key with the given alias, if present. Entries added
+// by AddKeystore are removed whatever the case of their alias.
+func (k *KeystoreBuilder) RemoveEntry(alias string) {
+	delete(k.keyPairs, alias)
+	delete(k.trustedCerts, alias)
+	delete(k.secretKeys, alias)
+	k.removeImported(alias)
+}
+
+// removeImported removes the entries added by AddKeystore whose aliases match alias, ignoring case.
+func (k *KeystoreBuilder) removeImported(alias string) {
+	normalized := NormalizeAlias(alias)
+	for existing, kp := range k.keyPairs {
+		if kp.imported && NormalizeAlias(existing) == normalized {
+			delete(k.keyPairs, existing)
+		}
+	}
+	for existing, tc := range k.trustedCerts {
+		if tc.imported && NormalizeAlias(existing) == normalized {
+			delete(k.trustedCerts, existing)
+		}
+	}
+	for existing, sk := range k.secretKeys {
+		if sk.imported && NormalizeAlias(existing) == normalized {
+			delete(k.secretKeys, existing)
+		}
+	}
+}
+
 // SetPassword sets the keystore password.
 func (k *KeystoreBuilder) SetPassword(password string) {
 	k.password = password
 }
 
+// SetStoreType sets the format of the generated keystore, defaults to StoreTypeJKS.
+func (k *KeystoreBuilder) SetStoreType(storeType StoreType) {
+	k.storeType = storeType
+}
+
+/*
+SetDeterministic enables or disables deterministic builds.
+When enabled, the same builder contents always produce the same keystore: key protection salts are derived
+from the password & entry contents, and entry timestamps are taken from the NotBefore time of each entry's
+certificate rather than the current time. Secret key entries, which have no certificate, use the Unix epoch.
+*/
+func (k *KeystoreBuilder) SetDeterministic(deterministic bool) {
+	k.deterministic = deterministic
+}
+
+/*
+SetBuildChains enables or disables automatic certificate chain building.
+When enabled, the CA certificates of each key pair are treated as an unordered pool: the chain is built from the
+leaf certificate by linking each certificate to its issuer using names, key identifiers & signatures, and any
+certificates that are not part of the chain are dropped. Build returns ErrIncompleteChain if CA certificates are
+given but none issued the leaf certificate, or if a CA certificate named as an issuer did not issue the chain.
+When disabled, CA certificates are added to chains in the given order.
+*/
+func (k *KeystoreBuilder) SetBuildChains(buildChains bool) {
+	k.buildChains = buildChains
+}
+
+/*
+SetMinRemainingValidity sets the minimum remaining validity of key pair certificates, including CA certificates.
+Build adds a warning wrapping ErrCertExpiring for each certificate that expires within the duration, see Warnings.
+Certificates that are expired or not yet valid are always an error. Key pairs added by AddKeystore are not checked.
+*/
+func (k *KeystoreBuilder) SetMinRemainingValidity(minValidity time.Duration) {
+	k.minValidity = minValidity
+}
+
+/*
+SetSkipValidityChecks enables or disables skipping the validity period checks of key pair certificates.
+When enabled, Build accepts expired & not yet valid certificates without warnings, so its result does not depend
+on the current time, e.g. for deterministic builds of keystores whose certificates may expire. Chains verified
+against trusted roots are still checked, see SetTrustedRoots.
+*/
+func (k *KeystoreBuilder) SetSkipValidityChecks(skip bool) {
+	k.skipValidityChecks = skip
+}
+
+/*
+CheckValidity checks the validity periods of a key pair's certificate chain against the current time, as Build does
+for every key pair not added by AddKeystore, e.g. to report expiring certificates before building.
+The chain is built as it would be by Build, so it is ordered first if SetBuildChains is enabled.
+
+Returns an error wrapping ErrCertExpired or ErrCertNotYetValid if a certificate is expired or not yet valid, and a
+warning wrapping ErrCertExpiring for each certificate that expires within the minimum remaining validity.
+
+Parameters:
+
+	`alias` - Alias for cert/key pair
+*/
+func (k *KeystoreBuilder) CheckValidity(alias string) ([]error, error) {
+	kp, ok := k.keyPairs[alias]
+	if !ok {
+		return nil, fmt.Errorf("no key pair found for alias %q", alias)
+	}
+	return kp.checkValidity(alias, k.buildChains, time.Now(), k.minValidity)
+}
+
+// Warnings returns the warnings found by the last call to Build, e.g. certificates that expire soon.
+func (k *KeystoreBuilder) Warnings() []error {
+	return k.warnings
+}
+
 // Build constructs the keystore from the builder contents.
 func (k *KeystoreBuilder) Build() ([]byte, error) {
 	// Validate builder contents
@@ -50,17 +398,39 @@ func (k *KeystoreBuilder) Build() ([]byte, error) {
 		return nil, fmt.Errorf("error generating key pairs: %w", err)
 	}
 
-	// Create key store
-	ks := &jks.Keystore{
-		Keypairs: keyPairs,
+	// Convert internal trusted certs to certificate entries
+	certs, err := k.genTrustedCerts()
+	if err != nil {
+		return nil, fmt.Errorf("error generating trusted certificates: %w", err)
 	}
 
+	// Convert internal secret keys to secret key entries
+	secretKeys := k.genSecretKeys()
+
 	// pack the keystore
-	ksByt, err := ks.Pack(&jks.Options{
-		Password: k.password,
-	})
+	var (
+		ksByt  []byte
+		format string
+	)
+	switch k.storeType {
+	case StoreTypePKCS12:
+		format = "PKCS#12"
+		ksByt, err = k.packPKCS12(certs, keyPairs, secretKeys)
+	case StoreTypeJCEKS:
+		format = "JCEKS"
+		ksByt, err = k.packJKS(certs, keyPairs, secretKeys)
+	case StoreTypeBKS, StoreTypeBKSV1:
+		format = "BKS"
+		ksByt, err = k.packBKS(certs, keyPairs, secretKeys)
+	case StoreTypeUBER:
+		format = "UBER"
+		ksByt, err = k.packBKS(certs, keyPairs, secretKeys)
+	default:
+		format = "JKS"
+		ksByt, err = k.packJKS(certs, keyPairs, secretKeys)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error converting keystore to JKS: %w", err)
+		return nil, fmt.Errorf("error converting keystore to %s: %w", format, err)
 	}
 
 	return ksByt, nil
@@ -68,15 +438,22 @@ func (k *KeystoreBuilder) Build() ([]byte, error) {
 
 func (k *KeystoreBuilder) genKeyPairs() ([]*jks.Keypair, error) {
 	kps := make([]*jks.Keypair, 0, len(k.keyPairs))
+	now := time.Now()
 
-	// Add certs
-	for alias, kp := range k.keyPairs {
+	// Add certs, ordered by alias so that entry order is stable
+	for _, alias := range sortedAliases(k.keyPairs) {
 		// Generate key pair
-		jksKp, err := kp.toJKSKeypair(alias)
+		jksKp, err := k.keyPairs[alias].toJKSKeypair(alias, k.buildChains)
 		if err != nil {
 			return nil, fmt.Errorf("error generating key pair for certificate %q: %w", alias, err)
 		}
 
+		// set creation time
+		jksKp.Timestamp = now
+		if k.deterministic {
+			jksKp.Timestamp = jksKp.CertChain[0].Cert.NotBefore
+		}
+
 		// add keypair to keystore
 		kps = append(kps, jksKp)
 	}
@@ -84,27 +461,165 @@ func (k *KeystoreBuilder) genKeyPairs() ([]*jks.Keypair, error) {
 	return kps, nil
 }
 
-// validate validates the contents of the builder.
+func (k *KeystoreBuilder) genTrustedCerts() ([]*jks.Cert, error) {
+	certs := make([]*jks.Cert, 0, len(k.trustedCerts))
+	now := time.Now()
+
+	// Add certs, ordered by alias so that entry order is stable
+	for _, alias := range sortedAliases(k.trustedCerts) {
+		crt, err := parseCertPEM(k.trustedCerts[alias].cert)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing trusted certificate %q: %w", alias, err)
+		}
+
+		// set creation time
+		ts := now
+		if k.deterministic {
+			ts = crt.NotBefore
+		}
+
+		certs = append(certs, &jks.Cert{
+			Alias:     alias,
+			Timestamp: ts,
+			Raw:       crt.Raw,
+			Cert:      crt,
+		})
+	}
+
+	return certs, nil
+}
+
+func (k *KeystoreBuilder) genSecretKeys() []*secretKeyEntry {
+	keys := make([]*secretKeyEntry, 0, len(k.secretKeys))
+	now := time.Now()
+
+	// Add keys, ordered by alias so that entry order is stable
+	for _, alias := range sortedAliases(k.secretKeys) {
+		// set creation time
+		ts := now
+		if k.deterministic {
+			ts = time.Unix(0, 0)
+		}
+
+		keys = append(keys, &secretKeyEntry{
+			Alias:     alias,
+			Timestamp: ts,
+			Algorithm: k.secretKeys[alias].algorithm,
+			Key:       k.secretKeys[alias].key,
+		})
+	}
+
+	return keys
+}
+
+// keyPassword returns the password protecting the private or secret key with the alias, defaulting to the store
+// password.
+func (k *KeystoreBuilder) keyPassword(alias string) string {
+	if password := k.keyPairs[alias].keyPassword; password != "" {
+		return password
+	}
+	if password := k.secretKeys[alias].keyPassword; password != "" {
+		return password
+	}
+	return k.password
+}
+
+// sortedAliases returns the aliases of a map of entries in sorted order.
+func sortedAliases[T any](entries map[string]T) []string {
+	aliases := make([]string, 0, len(entries))
+	for alias := range entries {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// validate validates the contents of the builder, recording any warnings.
 func (k *KeystoreBuilder) validate() error {
+	k.warnings = nil
+
 	if k.password == "" {
 		return ErrNoPassword
 	}
 
-	for alias, kp := range k.keyPairs {
-		if alias == "" {
-			return ErrInvalidAlias
+	switch k.storeType {
+	case StoreTypeJKS, StoreTypePKCS12, StoreTypeBKS, StoreTypeBKSV1, StoreTypeUBER:
+	case StoreTypeJCEKS:
+		if !isPrintableASCII(k.password) {
+			return fmt.Errorf("%w: JCEKS key protection requires a printable ASCII password", ErrInvalidPassword)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidStoreType, k.storeType)
+	}
+
+	// check aliases, ordered so that collision errors are stable
+	aliases := append(sortedAliases(k.keyPairs), sortedAliases(k.trustedCerts)...)
+	aliases = append(aliases, sortedAliases(k.secretKeys)...)
+	for _, alias := range aliases {
+		if err := ValidateAlias(alias); err != nil {
+			return err
 		}
+	}
+	if err := checkAliasCollisions(aliases); err != nil {
+		return err
+	}
+
+	// check key pairs, ordered by alias so that errors are stable
+	for _, alias := range sortedAliases(k.keyPairs) {
+		kp := k.keyPairs[alias]
 		if len(kp.cert) == 0 {
 			return fmt.Errorf("certificate is empty for alias %q", alias)
 		}
 		if len(kp.key) == 0 {
 			return fmt.Errorf("key is empty for alias %q", alias)
 		}
+		if k.storeType == StoreTypeJCEKS && !isPrintableASCII(k.keyPassword(alias)) {
+			return fmt.Errorf("%w: JCEKS key protection requires a printable ASCII key password for alias %q", ErrInvalidPassword, alias)
+		}
 		for i, caCert := range kp.caCerts {
 			if len(caCert) == 0 {
 				return fmt.Errorf("CA certificate %d for alias %q is empty", i, alias)
 			}
 		}
+		if err := kp.checkKey(alias); err != nil {
+			return err
+		}
+		if len(kp.trustedRoots) > 0 {
+			if err := k.VerifyChain(alias, kp.trustedRoots); err != nil {
+				return err
+			}
+		}
+	}
+
+	// check validity of caller supplied certificates, ordered by alias so that warnings are stable
+	now := time.Now()
+	for _, alias := range sortedAliases(k.keyPairs) {
+		if k.keyPairs[alias].imported || k.skipValidityChecks {
+			continue
+		}
+		warnings, err := k.keyPairs[alias].checkValidity(alias, k.buildChains, now, k.minValidity)
+		if err != nil {
+			return err
+		}
+		k.warnings = append(k.warnings, warnings...)
+	}
+
+	for _, alias := range sortedAliases(k.trustedCerts) {
+		if len(k.trustedCerts[alias].cert) == 0 {
+			return fmt.Errorf("trusted certificate is empty for alias %q", alias)
+		}
+	}
+
+	if len(k.secretKeys) > 0 && !k.storeType.SupportsSecretKeys() {
+		return fmt.Errorf("%w: %q, use one of %q", ErrNoSecretKeys, k.storeType, SecretKeyStoreTypes)
+	}
+	for _, alias := range sortedAliases(k.secretKeys) {
+		if err := k.secretKeys[alias].checkSecretKey(alias); err != nil {
+			return err
+		}
+		if k.storeType == StoreTypeJCEKS && !isPrintableASCII(k.keyPassword(alias)) {
+			return fmt.Errorf("%w: JCEKS key protection requires a printable ASCII key password for alias %q", ErrInvalidPassword, alias)
+		}
 	}
 
 	return nil
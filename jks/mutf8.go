@@ -0,0 +1,82 @@
+package jks
+
+import (
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// errInvalidModifiedUTF8 is returned when a string read from a keystore is not valid modified UTF-8.
+var errInvalidModifiedUTF8 = errors.New("invalid modified UTF-8 string")
+
+// encodeModifiedUTF8 encodes s as Java's modified UTF-8, as used by DataOutput.writeUTF.
+// It differs from standard UTF-8 in encoding U+0000 as two bytes & characters outside the Basic Multilingual
+// Plane as a UTF-16 surrogate pair, each half written as a three byte sequence (CESU-8).
+func encodeModifiedUTF8(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == 0:
+			out = append(out, 0xC0, 0x80)
+		case r < 0x80:
+			out = append(out, byte(r))
+		case r < 0x800:
+			out = append(out, 0xC0|byte(r>>6), 0x80|byte(r&0x3F))
+		case r < 0x10000:
+			out = appendModifiedUTF8Unit(out, uint16(r))
+		default:
+			hi, lo := utf16.EncodeRune(r)
+			out = appendModifiedUTF8Unit(out, uint16(hi))
+			out = appendModifiedUTF8Unit(out, uint16(lo))
+		}
+	}
+	return out
+}
+
+// appendModifiedUTF8Unit appends a UTF-16 code unit as a three byte sequence.
+func appendModifiedUTF8Unit(out []byte, u uint16) []byte {
+	return append(out, 0xE0|byte(u>>12), 0x80|byte((u>>6)&0x3F), 0x80|byte(u&0x3F))
+}
+
+// decodeModifiedUTF8 decodes Java's modified UTF-8, as read by DataInput.readUTF.
+// Four byte standard UTF-8 sequences are also accepted, so stores written by tools that don't use
+// modified UTF-8 can still be read.
+func decodeModifiedUTF8(b []byte) (string, error) {
+	units := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xE0 == 0xC0:
+			if i+1 >= len(b) || !isContinuation(b[i+1]) {
+				return "", errInvalidModifiedUTF8
+			}
+			units = append(units, uint16(c&0x1F)<<6|uint16(b[i+1]&0x3F))
+			i += 2
+		case c&0xF0 == 0xE0:
+			if i+2 >= len(b) || !isContinuation(b[i+1]) || !isContinuation(b[i+2]) {
+				return "", errInvalidModifiedUTF8
+			}
+			units = append(units, uint16(c&0x0F)<<12|uint16(b[i+1]&0x3F)<<6|uint16(b[i+2]&0x3F))
+			i += 3
+		case c&0xF8 == 0xF0:
+			r, size := utf8.DecodeRune(b[i:])
+			if r == utf8.RuneError {
+				return "", errInvalidModifiedUTF8
+			}
+			hi, lo := utf16.EncodeRune(r)
+			units = append(units, uint16(hi), uint16(lo))
+			i += size
+		default:
+			return "", errInvalidModifiedUTF8
+		}
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// isContinuation returns true if c is a UTF-8 continuation byte.
+func isContinuation(c byte) bool {
+	return c&0xC0 == 0x80
+}
@@ -0,0 +1,226 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lwithers/minijks/jks"
+)
+
+const (
+	// jceksMagic is written at the start of each JCEKS file.
+	jceksMagic uint32 = 0xCECECECE
+	// jksVersion is the JKS file format version written by packJKS.
+	jksVersion uint32 = 2
+	// jksTagPrivateKey marks a private key entry.
+	jksTagPrivateKey uint32 = 1
+	// jksTagTrustedCert marks a trusted certificate entry.
+	jksTagTrustedCert uint32 = 2
+	// jksTagSecretKey marks a sealed secret key entry, which is only supported by JCEKS.
+	jksTagSecretKey uint32 = 3
+)
+
+// asn1NULL is used for empty algorithm parameters, as expected by Java.
+var asn1NULL = asn1.RawValue{FullBytes: []byte{0x05, 0x00}}
+
+// packJKS serialises trusted certificates, key pairs & secret keys to a JKS or JCEKS keystore, which only differ in
+// their magic number & private key protection. Secret keys are only supported by JCEKS.
+// Unlike jks.Keystore.Pack, the key protection salt is taken from the builder, so output can be reproducible.
+func (k *KeystoreBuilder) packJKS(certs []*jks.Cert, keyPairs []*jks.Keypair, secretKeys []*secretKeyEntry) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// write header
+	magic := jks.MagicNumber
+	if k.storeType == StoreTypeJCEKS {
+		magic = jceksMagic
+	}
+	writeUint32(&buf, magic)
+	writeUint32(&buf, jksVersion)
+	writeUint32(&buf, uint32(len(certs)+len(keyPairs)+len(secretKeys)))
+
+	// write entries
+	for _, cert := range certs {
+		if err := writeTrustedCert(&buf, cert); err != nil {
+			return nil, fmt.Errorf("error writing trusted certificate %q: %w", cert.Alias, err)
+		}
+	}
+	for _, kp := range keyPairs {
+		if err := k.writeKeyPair(&buf, kp); err != nil {
+			return nil, fmt.Errorf("error writing key pair %q: %w", kp.Alias, err)
+		}
+	}
+	for _, sk := range secretKeys {
+		if err := k.writeSecretKey(&buf, sk); err != nil {
+			return nil, fmt.Errorf("error writing secret key %q: %w", sk.Alias, err)
+		}
+	}
+
+	// append integrity digest
+	buf.Write(jks.ComputeDigest(buf.Bytes(), k.password))
+
+	return buf.Bytes(), nil
+}
+
+// writeTrustedCert writes a trusted certificate entry.
+func writeTrustedCert(w io.Writer, cert *jks.Cert) error {
+	writeUint32(w, jksTagTrustedCert)
+	if err := writeStr(w, cert.Alias); err != nil {
+		return fmt.Errorf("error writing alias: %w", err)
+	}
+	writeTimestamp(w, cert.Timestamp)
+
+	return writeCert(w, cert.Cert)
+}
+
+// writeKeyPair writes a private key entry & its certificate chain.
+func (k *KeystoreBuilder) writeKeyPair(w io.Writer, kp *jks.Keypair) error {
+	writeUint32(w, jksTagPrivateKey)
+	if err := writeStr(w, kp.Alias); err != nil {
+		return fmt.Errorf("error writing alias: %w", err)
+	}
+	writeTimestamp(w, kp.Timestamp)
+
+	// marshal private key to PKCS#8
+	plaintext, err := x509.MarshalPKCS8PrivateKey(kp.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("error marshalling private key: %w", err)
+	}
+
+	// protect private key with the key password
+	encKey, err := k.protectKey(kp.Alias, plaintext)
+	if err != nil {
+		return fmt.Errorf("error protecting private key: %w", err)
+	}
+	writeBytes(w, encKey)
+
+	// write certificate chain
+	writeUint32(w, uint32(len(kp.CertChain)))
+	for _, cert := range kp.CertChain {
+		if err := writeCert(w, cert.Cert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSecretKey writes a JCEKS secret key entry, sealed as a serialized Java object.
+func (k *KeystoreBuilder) writeSecretKey(w io.Writer, sk *secretKeyEntry) error {
+	writeUint32(w, jksTagSecretKey)
+	if err := writeStr(w, sk.Alias); err != nil {
+		return fmt.Errorf("error writing alias: %w", err)
+	}
+	writeTimestamp(w, sk.Timestamp)
+
+	salt, err := k.salt(jceksKeyProtectorSaltLen, []byte("jceks secret key"), []byte(sk.Alias), sk.Key)
+	if err != nil {
+		return fmt.Errorf("error generating salt: %w", err)
+	}
+	sealed, err := jceksSealKey(sk, k.keyPassword(sk.Alias), salt)
+	if err != nil {
+		return fmt.Errorf("error sealing secret key: %w", err)
+	}
+	_, _ = w.Write(sealed)
+
+	return nil
+}
+
+// protectKey encrypts a PKCS#8 private key with the key protector for the store type.
+// Returns the marshalled PKCS#8 EncryptedPrivateKeyInfo.
+func (k *KeystoreBuilder) protectKey(alias string, plaintext []byte) ([]byte, error) {
+	var keyInfo jks.EncryptedPrivateKeyInfo
+
+	switch k.storeType {
+	case StoreTypeJCEKS:
+		salt, err := k.salt(jceksKeyProtectorSaltLen, []byte("jceks key"), []byte(alias), plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("error generating salt: %w", err)
+		}
+		params, err := asn1.Marshal(pbeParameter{
+			Salt:           salt,
+			IterationCount: jceksKeyProtectorIterations,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling key protection parameters: %w", err)
+		}
+		ciphertext, err := jceksProtectKey(plaintext, k.keyPassword(alias), salt, jceksKeyProtectorIterations)
+		if err != nil {
+			return nil, err
+		}
+
+		keyInfo = jks.EncryptedPrivateKeyInfo{
+			Algo: pkix.AlgorithmIdentifier{
+				Algorithm:  jks.JavaKeyEncryptionOID2,
+				Parameters: asn1.RawValue{FullBytes: params},
+			},
+			EncryptedData: ciphertext,
+		}
+	default:
+		salt, err := k.salt(jksKeyProtectorSaltLen, []byte("jks key"), []byte(alias), plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("error generating salt: %w", err)
+		}
+
+		keyInfo = jks.EncryptedPrivateKeyInfo{
+			Algo: pkix.AlgorithmIdentifier{
+				Algorithm:  jks.JavaKeyEncryptionOID1,
+				Parameters: asn1NULL,
+			},
+			EncryptedData: jksProtectKey(plaintext, k.keyPassword(alias), salt),
+		}
+	}
+
+	encKey, err := asn1.Marshal(keyInfo)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling encrypted private key: %w", err)
+	}
+	return encKey, nil
+}
+
+// writeCert writes a certificate with its type header.
+func writeCert(w io.Writer, cert *x509.Certificate) error {
+	if err := writeStr(w, jks.CertType); err != nil {
+		return fmt.Errorf("error writing certificate type: %w", err)
+	}
+	writeBytes(w, cert.Raw)
+	return nil
+}
+
+// writeUint32 writes a big-endian 32-bit unsigned integer.
+func writeUint32(w io.Writer, u uint32) {
+	_ = binary.Write(w, binary.BigEndian, u)
+}
+
+// writeUint64 writes a big-endian 64-bit unsigned integer.
+func writeUint64(w io.Writer, u uint64) {
+	_ = binary.Write(w, binary.BigEndian, u)
+}
+
+// writeTimestamp writes a timestamp as big-endian milliseconds since the Unix epoch.
+func writeTimestamp(w io.Writer, ts time.Time) {
+	writeUint64(w, uint64(ts.UnixMilli()))
+}
+
+// writeBytes writes a byte slice prefixed with its 32-bit length.
+func writeBytes(w io.Writer, b []byte) {
+	writeUint32(w, uint32(len(b)))
+	_, _ = w.Write(b)
+}
+
+// writeStr writes a string as modified UTF-8 prefixed with its 16-bit length, as Java's DataOutput.writeUTF.
+func writeStr(w io.Writer, s string) error {
+	b := encodeModifiedUTF8(s)
+	if len(b) > 0xFFFF {
+		return errors.New("string too long")
+	}
+	_ = binary.Write(w, binary.BigEndian, uint16(len(b)))
+	_, _ = w.Write(b)
+	return nil
+}
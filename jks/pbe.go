@@ -0,0 +1,326 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"hash"
+
+	"github.com/lwithers/minijks/jks"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// pbeIterations is the iteration count used for password based encryption & MACs.
+	pbeIterations = 10000
+	// pbeSaltLen is the salt length used for password based encryption & MACs.
+	pbeSaltLen = 16
+	// pkcs12KDFKeyID is the PKCS#12 key derivation ID for encryption keys.
+	pkcs12KDFKeyID = 1
+	// pkcs12KDFIVID is the PKCS#12 key derivation ID for initialisation vectors.
+	pkcs12KDFIVID = 2
+	// pkcs12KDFMACID is the PKCS#12 key derivation ID for MAC keys.
+	pkcs12KDFMACID = 3
+	// maxPBEIterations is the maximum iteration count accepted when reading keystores, matching Java's limit.
+	maxPBEIterations = 5000000
+)
+
+var (
+	// RFC 8018 PBES2 & PBKDF2.
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	// RFC 8018 HMAC PRFs.
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA224 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	// NIST AES in CBC mode.
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	// RFC 8018 triple DES in CBC mode.
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	// RFC 7292 PKCS#12 password based encryption.
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	// NIST & OIW digests.
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+type (
+	// pbes2Params is the RFC 8018 PBES2-params structure.
+	pbes2Params struct {
+		KeyDerivationFunc pkix.AlgorithmIdentifier
+		EncryptionScheme  pkix.AlgorithmIdentifier
+	}
+
+	// pbkdf2Params is the RFC 8018 PBKDF2-params structure.
+	pbkdf2Params struct {
+		Salt           []byte
+		IterationCount int
+		KeyLength      int                      `asn1:"optional"`
+		PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+	}
+)
+
+// pbes2Encrypt encrypts plaintext with PBES2, using PBKDF2 with HMAC-SHA256 & AES-256-CBC.
+// Returns the algorithm identifier describing the encryption parameters, and the ciphertext.
+func pbes2Encrypt(plaintext []byte, password string, salt, iv []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	// derive key
+	key := pbkdf2.Key([]byte(password), salt, pbeIterations, 32, sha256.New)
+
+	// encrypt padded plaintext
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	ciphertext := pkcs7Pad(plaintext, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+
+	// marshal parameters
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbeIterations,
+		PRF: pkix.AlgorithmIdentifier{
+			Algorithm:  oidHMACWithSHA256,
+			Parameters: asn1NULL,
+		},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("error marshalling PBKDF2 parameters: %w", err)
+	}
+	encIV, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("error marshalling IV: %w", err)
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBKDF2,
+			Parameters: asn1.RawValue{FullBytes: kdfParams},
+		},
+		EncryptionScheme: pkix.AlgorithmIdentifier{
+			Algorithm:  oidAES256CBC,
+			Parameters: asn1.RawValue{FullBytes: encIV},
+		},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("error marshalling PBES2 parameters: %w", err)
+	}
+
+	return pkix.AlgorithmIdentifier{
+		Algorithm:  oidPBES2,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}, ciphertext, nil
+}
+
+// pbeDecrypt decrypts ciphertext encrypted with a PBES2 or PKCS#12 password based encryption scheme, as used
+// for PKCS#12 contents & shrouded keys.
+func pbeDecrypt(algo pkix.AlgorithmIdentifier, ciphertext []byte, password string) ([]byte, error) {
+	switch {
+	case algo.Algorithm.Equal(oidPBES2):
+		return pbes2Decrypt(algo.Parameters.FullBytes, ciphertext, password)
+	case algo.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC),
+		algo.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2CBC),
+		algo.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		return pkcs12PBEDecrypt(algo, ciphertext, password)
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm %s", algo.Algorithm)
+	}
+}
+
+// pbes2Decrypt decrypts ciphertext with PBES2, using PBKDF2 with any HMAC-SHA PRF & AES-CBC or triple DES.
+func pbes2Decrypt(params, ciphertext []byte, password string) ([]byte, error) {
+	var p pbes2Params
+	if err := unmarshalDER(params, &p); err != nil {
+		return nil, fmt.Errorf("error unmarshalling PBES2 parameters: %w", err)
+	}
+
+	// read key derivation parameters
+	if !p.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function %s", p.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if err := unmarshalDER(p.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("error unmarshalling PBKDF2 parameters: %w", err)
+	}
+	if kdf.IterationCount < 1 || kdf.IterationCount > maxPBEIterations {
+		return nil, fmt.Errorf("invalid iteration count %d", kdf.IterationCount)
+	}
+	prf, err := pbkdf2PRF(kdf.PRF.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	// read encryption parameters
+	var (
+		newCipher func([]byte) (cipher.Block, error)
+		keyLen    int
+	)
+	switch enc := p.EncryptionScheme.Algorithm; {
+	case enc.Equal(oidAES128CBC):
+		newCipher, keyLen = aes.NewCipher, 16
+	case enc.Equal(oidAES192CBC):
+		newCipher, keyLen = aes.NewCipher, 24
+	case enc.Equal(oidAES256CBC):
+		newCipher, keyLen = aes.NewCipher, 32
+	case enc.Equal(oidDESEDE3CBC):
+		newCipher, keyLen = des.NewTripleDESCipher, 24
+	default:
+		return nil, fmt.Errorf("unsupported encryption scheme %s", enc)
+	}
+	var iv []byte
+	if err := unmarshalDER(p.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("error unmarshalling IV: %w", err)
+	}
+
+	// derive key & decrypt
+	block, err := newCipher(pbkdf2.Key([]byte(password), kdf.Salt, kdf.IterationCount, keyLen, prf))
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid IV length %d", len(iv))
+	}
+	return cbcDecrypt(block, iv, ciphertext)
+}
+
+// pbkdf2PRF returns the hash function for a PBKDF2 PRF, which defaults to HMAC-SHA1 when not set.
+func pbkdf2PRF(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case len(oid) == 0, oid.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case oid.Equal(oidHMACWithSHA224):
+		return sha256.New224, nil
+	case oid.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case oid.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case oid.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF %s", oid)
+	}
+}
+
+// pkcs12PBEDecrypt decrypts ciphertext with the legacy RFC 7292 appendix C PKCS#12 password based encryption
+// schemes, which are still the defaults for Java 8 & OpenSSL 1.
+func pkcs12PBEDecrypt(algo pkix.AlgorithmIdentifier, ciphertext []byte, password string) ([]byte, error) {
+	var params pbeParameter
+	if err := unmarshalDER(algo.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("error unmarshalling PBE parameters: %w", err)
+	}
+	if params.IterationCount < 1 || params.IterationCount > maxPBEIterations {
+		return nil, fmt.Errorf("invalid iteration count %d", params.IterationCount)
+	}
+	pw := pkcs12Password(password)
+	kdf := func(id byte, size int) []byte {
+		return pkcs12KDF(sha1.New, 64, pw, params.Salt, params.IterationCount, id, size)
+	}
+
+	var block cipher.Block
+	switch {
+	case algo.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		var err error
+		if block, err = des.NewTripleDESCipher(kdf(pkcs12KDFKeyID, 24)); err != nil {
+			return nil, err
+		}
+	case algo.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2CBC):
+		block = newRC2Cipher(kdf(pkcs12KDFKeyID, 16), 128)
+	default:
+		block = newRC2Cipher(kdf(pkcs12KDFKeyID, 5), 40)
+	}
+
+	return cbcDecrypt(block, kdf(pkcs12KDFIVID, block.BlockSize()), ciphertext)
+}
+
+// cbcDecrypt decrypts PKCS#7 padded ciphertext in CBC mode. As padding is the only integrity check for password
+// based encryption, invalid padding is reported as ErrIntegrityCheck.
+func cbcDecrypt(block cipher.Block, iv, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("ciphertext length %d is not a multiple of the block size", len(ciphertext))
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	// remove padding
+	padLen := int(plaintext[len(plaintext)-1])
+	if padLen == 0 || padLen > block.BlockSize() ||
+		!bytes.Equal(plaintext[len(plaintext)-padLen:], bytes.Repeat([]byte{byte(padLen)}, padLen)) {
+		return nil, ErrIntegrityCheck
+	}
+	return plaintext[:len(plaintext)-padLen], nil
+}
+
+// pkcs12Password encodes a password as a null terminated BMPString, as used by the PKCS#12 key derivation function.
+func pkcs12Password(password string) []byte {
+	return append(jks.PasswordUTF16(password), 0, 0)
+}
+
+// pkcs12KDF derives size bytes of key material with the RFC 7292 appendix B key derivation function.
+// v is the block size of the hash function in bytes, id selects the purpose of the material.
+func pkcs12KDF(h func() hash.Hash, v int, password, salt []byte, iterations int, id byte, size int) []byte {
+	u := h().Size()
+
+	// concatenate diversifier, salt & password, each extended to a multiple of v bytes
+	d := bytes.Repeat([]byte{id}, v)
+	i := append(fillBlocks(salt, v), fillBlocks(password, v)...)
+
+	out := make([]byte, 0, size+u)
+	for len(out) < size {
+		// A = H^iterations(D || I)
+		md := h()
+		md.Write(d)
+		md.Write(i)
+		a := md.Sum(nil)
+		for j := 1; j < iterations; j++ {
+			md.Reset()
+			md.Write(a)
+			a = md.Sum(a[:0])
+		}
+		out = append(out, a...)
+
+		// I_j = (I_j + B + 1) mod 2^(8v), where B is A extended to v bytes
+		b := fillBlocks(a, v)[:v]
+		for j := 0; j < len(i); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				carry += int(i[j+k]) + int(b[k])
+				i[j+k] = byte(carry)
+				carry >>= 8
+			}
+		}
+	}
+
+	return out[:size]
+}
+
+// fillBlocks repeats data to fill a whole number of v byte blocks.
+func fillBlocks(data []byte, v int) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+	n := v * ((len(data) + v - 1) / v)
+	out := make([]byte, n)
+	for i := 0; i < n; i += len(data) {
+		copy(out[i:], data)
+	}
+	return out
+}
+
+// pkcs7Pad returns a copy of data padded to a multiple of the block size.
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padLen := blockSize - len(data)%blockSize
+	return append(append(make([]byte, 0, len(data)+padLen), data...), bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+}
@@ -0,0 +1,446 @@
+package jks
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/lwithers/minijks/jks"
+)
+
+// pkcs12Version is the PFX version written by packPKCS12.
+const pkcs12Version = 3
+
+var (
+	// RFC 2315 content types.
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	// RFC 7292 bag types.
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidSecretBag           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 5}
+	oidX509Certificate     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	// RFC 2985 attributes.
+	oidFriendlyName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	// Oracle attribute marking a certificate as trusted by Java, & the any extended key usage value it holds.
+	oidJavaTrustedKeyUsage = asn1.ObjectIdentifier{2, 16, 840, 1, 113894, 746875, 1, 1}
+	oidAnyExtendedKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37, 0}
+)
+
+type (
+	// pfxPdu is the top level PKCS#12 structure.
+	pfxPdu struct {
+		Version  int
+		AuthSafe contentInfo
+		MacData  macData `asn1:"optional"`
+	}
+
+	// contentInfo is the PKCS#7 ContentInfo structure.
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+	}
+
+	// encryptedData is the PKCS#7 EncryptedData structure.
+	encryptedData struct {
+		Version              int
+		EncryptedContentInfo encryptedContentInfo
+	}
+
+	// encryptedContentInfo is the PKCS#7 EncryptedContentInfo structure.
+	encryptedContentInfo struct {
+		ContentType                asn1.ObjectIdentifier
+		ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedContent           []byte `asn1:"tag:0,optional"`
+	}
+
+	// macData holds the integrity MAC over the authenticated safe.
+	macData struct {
+		Mac        digestInfo
+		MacSalt    []byte
+		Iterations int `asn1:"optional,default:1"`
+	}
+
+	// digestInfo is the PKCS#1 DigestInfo structure.
+	digestInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		Digest    []byte
+	}
+
+	// safeBag is a single PKCS#12 bag.
+	safeBag struct {
+		ID         asn1.ObjectIdentifier
+		Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+		Attributes []pkcs12Attribute `asn1:"set,optional"`
+	}
+
+	// pkcs12Attribute is an attribute attached to a bag.
+	pkcs12Attribute struct {
+		ID    asn1.ObjectIdentifier
+		Value asn1.RawValue
+	}
+
+	// certBag holds a certificate.
+	certBag struct {
+		ID   asn1.ObjectIdentifier
+		Data []byte `asn1:"tag:0,explicit"`
+	}
+
+	// secretBag holds a secret. Java stores secret keys as an encrypted secretKeyInfo, marked as a shrouded key.
+	secretBag struct {
+		ID    asn1.ObjectIdentifier
+		Value []byte `asn1:"tag:0,explicit"`
+	}
+)
+
+// packPKCS12 serialises trusted certificates, key pairs & secret keys to a PKCS#12 keystore.
+// Private keys, secret keys & certificates are encrypted with PBES2 (PBKDF2 with HMAC-SHA256 & AES-256-CBC), and
+// the store is integrity protected with HMAC-SHA256, matching the defaults of Java 12+ & OpenSSL 3.
+func (k *KeystoreBuilder) packPKCS12(certs []*jks.Cert, keyPairs []*jks.Keypair, secretKeys []*secretKeyEntry) ([]byte, error) {
+	var (
+		keyBags  = make([]safeBag, 0, len(keyPairs)+len(secretKeys))
+		certBags = make([]safeBag, 0, len(certs)+len(keyPairs))
+		// seen tracks chain certificates already added, as chains may share intermediates
+		seen = make(map[[sha256.Size]byte]bool)
+	)
+
+	for _, kp := range keyPairs {
+		// leaf & key are linked by local key ID
+		leaf := kp.CertChain[0].Cert
+		localKeyID := sha1.Sum(leaf.Raw)
+		attrs, err := pkcs12EntryAttributes(kp.Alias, localKeyID[:])
+		if err != nil {
+			return nil, fmt.Errorf("error generating attributes for key pair %q: %w", kp.Alias, err)
+		}
+
+		// add private key
+		keyBag, err := k.pkcs12KeyBag(kp, attrs)
+		if err != nil {
+			return nil, fmt.Errorf("error generating key bag for key pair %q: %w", kp.Alias, err)
+		}
+		keyBags = append(keyBags, keyBag)
+
+		// add certificate chain
+		for i, cert := range kp.CertChain {
+			var bagAttrs []pkcs12Attribute
+			if i == 0 {
+				bagAttrs = attrs
+			} else {
+				fingerprint := sha256.Sum256(cert.Cert.Raw)
+				if seen[fingerprint] {
+					continue
+				}
+				seen[fingerprint] = true
+			}
+
+			bag, err := pkcs12CertBag(cert.Cert, bagAttrs)
+			if err != nil {
+				return nil, fmt.Errorf("error generating certificate bag for key pair %q: %w", kp.Alias, err)
+			}
+			certBags = append(certBags, bag)
+		}
+	}
+
+	for _, sk := range secretKeys {
+		bag, err := k.pkcs12SecretBag(sk)
+		if err != nil {
+			return nil, fmt.Errorf("error generating secret bag for secret key %q: %w", sk.Alias, err)
+		}
+		keyBags = append(keyBags, bag)
+	}
+
+	for _, cert := range certs {
+		attrs, err := pkcs12TrustedCertAttributes(cert.Alias)
+		if err != nil {
+			return nil, fmt.Errorf("error generating attributes for trusted certificate %q: %w", cert.Alias, err)
+		}
+
+		bag, err := pkcs12CertBag(cert.Cert, attrs)
+		if err != nil {
+			return nil, fmt.Errorf("error generating certificate bag for trusted certificate %q: %w", cert.Alias, err)
+		}
+		certBags = append(certBags, bag)
+	}
+
+	// build authenticated safe, with certificates encrypted & keys in shrouded or secret bags
+	authSafe := make([]contentInfo, 0, 2)
+	if len(certBags) > 0 {
+		ci, err := k.pkcs12EncryptedContentInfo(certBags)
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting certificates: %w", err)
+		}
+		authSafe = append(authSafe, ci)
+	}
+	if len(keyBags) > 0 {
+		ci, err := pkcs12DataContentInfo(keyBags)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding private keys: %w", err)
+		}
+		authSafe = append(authSafe, ci)
+	}
+	authSafeData, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling authenticated safe: %w", err)
+	}
+	authSafeContent, err := asn1.Marshal(authSafeData)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling authenticated safe: %w", err)
+	}
+
+	// generate MAC over authenticated safe
+	mac, err := k.pkcs12MAC(authSafeData)
+	if err != nil {
+		return nil, fmt.Errorf("error generating MAC: %w", err)
+	}
+
+	return asn1.Marshal(pfxPdu{
+		Version: pkcs12Version,
+		AuthSafe: contentInfo{
+			ContentType: oidDataContentType,
+			Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: authSafeContent},
+		},
+		MacData: mac,
+	})
+}
+
+// pkcs12KeyBag generates a shrouded key bag for a key pair.
+func (k *KeystoreBuilder) pkcs12KeyBag(kp *jks.Keypair, attrs []pkcs12Attribute) (safeBag, error) {
+	plaintext, err := x509.MarshalPKCS8PrivateKey(kp.PrivateKey)
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error marshalling private key: %w", err)
+	}
+
+	// encrypt private key
+	salt, err := k.salt(pbeSaltLen, []byte("pkcs12 key salt"), []byte(kp.Alias), plaintext)
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error generating salt: %w", err)
+	}
+	iv, err := k.salt(16, []byte("pkcs12 key iv"), []byte(kp.Alias), plaintext)
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error generating IV: %w", err)
+	}
+	algo, ciphertext, err := pbes2Encrypt(plaintext, k.keyPassword(kp.Alias), salt, iv)
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error encrypting private key: %w", err)
+	}
+
+	encKey, err := asn1.Marshal(jks.EncryptedPrivateKeyInfo{
+		Algo:          algo,
+		EncryptedData: ciphertext,
+	})
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error marshalling encrypted private key: %w", err)
+	}
+
+	return safeBag{
+		ID:         oidPKCS8ShroudedKeyBag,
+		Value:      asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: encKey},
+		Attributes: attrs,
+	}, nil
+}
+
+// pkcs12SecretBag generates a secret bag for a secret key, encrypted as Java does.
+func (k *KeystoreBuilder) pkcs12SecretBag(sk *secretKeyEntry) (safeBag, error) {
+	plaintext, err := asn1.Marshal(secretKeyInfo{
+		Algo: pkix.AlgorithmIdentifier{Algorithm: secretKeyAlgorithms[sk.Algorithm]},
+		Key:  sk.Key,
+	})
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error marshalling secret key: %w", err)
+	}
+
+	// encrypt secret key
+	salt, err := k.salt(pbeSaltLen, []byte("pkcs12 secret key salt"), []byte(sk.Alias), plaintext)
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error generating salt: %w", err)
+	}
+	iv, err := k.salt(16, []byte("pkcs12 secret key iv"), []byte(sk.Alias), plaintext)
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error generating IV: %w", err)
+	}
+	algo, ciphertext, err := pbes2Encrypt(plaintext, k.keyPassword(sk.Alias), salt, iv)
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error encrypting secret key: %w", err)
+	}
+	encKey, err := asn1.Marshal(jks.EncryptedPrivateKeyInfo{
+		Algo:          algo,
+		EncryptedData: ciphertext,
+	})
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error marshalling encrypted secret key: %w", err)
+	}
+
+	bag, err := asn1.Marshal(secretBag{
+		ID:    oidPKCS8ShroudedKeyBag,
+		Value: encKey,
+	})
+	if err != nil {
+		return safeBag{}, err
+	}
+
+	// Java ignores secret keys without a local key ID, which only needs to be unique as no certificate refers to it
+	localKeyID := sha1.Sum(encKey)
+	attrs, err := pkcs12EntryAttributes(sk.Alias, localKeyID[:])
+	if err != nil {
+		return safeBag{}, fmt.Errorf("error generating attributes: %w", err)
+	}
+
+	return safeBag{
+		ID:         oidSecretBag,
+		Value:      asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: bag},
+		Attributes: attrs,
+	}, nil
+}
+
+// pkcs12EncryptedContentInfo encrypts bags into an encrypted data content info.
+func (k *KeystoreBuilder) pkcs12EncryptedContentInfo(bags []safeBag) (contentInfo, error) {
+	plaintext, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, err
+	}
+
+	salt, err := k.salt(pbeSaltLen, []byte("pkcs12 content salt"), plaintext)
+	if err != nil {
+		return contentInfo{}, fmt.Errorf("error generating salt: %w", err)
+	}
+	iv, err := k.salt(16, []byte("pkcs12 content iv"), plaintext)
+	if err != nil {
+		return contentInfo{}, fmt.Errorf("error generating IV: %w", err)
+	}
+	algo, ciphertext, err := pbes2Encrypt(plaintext, k.password, salt, iv)
+	if err != nil {
+		return contentInfo{}, err
+	}
+
+	data, err := asn1.Marshal(encryptedData{
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidDataContentType,
+			ContentEncryptionAlgorithm: algo,
+			EncryptedContent:           ciphertext,
+		},
+	})
+	if err != nil {
+		return contentInfo{}, err
+	}
+
+	return contentInfo{
+		ContentType: oidEncryptedDataContentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: data},
+	}, nil
+}
+
+// pkcs12MAC generates the integrity MAC over the authenticated safe.
+func (k *KeystoreBuilder) pkcs12MAC(authSafe []byte) (macData, error) {
+	salt, err := k.salt(pbeSaltLen, []byte("pkcs12 mac salt"), authSafe)
+	if err != nil {
+		return macData{}, fmt.Errorf("error generating salt: %w", err)
+	}
+
+	key := pkcs12KDF(sha256.New, 64, pkcs12Password(k.password), salt, pbeIterations, pkcs12KDFMACID, sha256.Size)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(authSafe)
+
+	return macData{
+		Mac: digestInfo{
+			Algorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidSHA256,
+				Parameters: asn1NULL,
+			},
+			Digest: mac.Sum(nil),
+		},
+		MacSalt:    salt,
+		Iterations: pbeIterations,
+	}, nil
+}
+
+// pkcs12DataContentInfo encodes bags into an unencrypted data content info.
+func pkcs12DataContentInfo(bags []safeBag) (contentInfo, error) {
+	data, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	content, err := asn1.Marshal(data)
+	if err != nil {
+		return contentInfo{}, err
+	}
+
+	return contentInfo{
+		ContentType: oidDataContentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	}, nil
+}
+
+// pkcs12CertBag generates a certificate bag.
+func pkcs12CertBag(cert *x509.Certificate, attrs []pkcs12Attribute) (safeBag, error) {
+	bag, err := asn1.Marshal(certBag{
+		ID:   oidX509Certificate,
+		Data: cert.Raw,
+	})
+	if err != nil {
+		return safeBag{}, err
+	}
+
+	return safeBag{
+		ID:         oidCertBag,
+		Value:      asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: bag},
+		Attributes: attrs,
+	}, nil
+}
+
+// pkcs12EntryAttributes generates the friendly name & local key ID attributes for a key pair.
+func pkcs12EntryAttributes(alias string, localKeyID []byte) ([]pkcs12Attribute, error) {
+	name, err := pkcs12FriendlyName(alias)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := pkcs12AttributeValue(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return []pkcs12Attribute{
+		{ID: oidFriendlyName, Value: name},
+		{ID: oidLocalKeyID, Value: keyID},
+	}, nil
+}
+
+// pkcs12TrustedCertAttributes generates the friendly name & Java trust attributes for a trusted certificate.
+func pkcs12TrustedCertAttributes(alias string) ([]pkcs12Attribute, error) {
+	name, err := pkcs12FriendlyName(alias)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := pkcs12AttributeValue(oidAnyExtendedKeyUsage)
+	if err != nil {
+		return nil, err
+	}
+
+	return []pkcs12Attribute{
+		{ID: oidFriendlyName, Value: name},
+		{ID: oidJavaTrustedKeyUsage, Value: usage},
+	}, nil
+}
+
+// pkcs12FriendlyName encodes an alias as a friendly name attribute value.
+func pkcs12FriendlyName(alias string) (asn1.RawValue, error) {
+	return pkcs12AttributeValue(asn1.RawValue{Tag: asn1.TagBMPString, Bytes: jks.PasswordUTF16(alias)})
+}
+
+// pkcs12AttributeValue marshals a single attribute value into a SET.
+func pkcs12AttributeValue(val any) (asn1.RawValue, error) {
+	data, err := asn1.Marshal(val)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	set, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: data})
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{FullBytes: set}, nil
+}
@@ -0,0 +1,355 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+
+	"github.com/lwithers/minijks/jks"
+)
+
+type (
+	// pkcs12Key is a private key read from a PKCS#12 store.
+	pkcs12Key struct {
+		key        any
+		name       string
+		localKeyID []byte
+	}
+
+	// pkcs12Cert is a certificate read from a PKCS#12 store.
+	pkcs12Cert struct {
+		cert       *x509.Certificate
+		name       string
+		localKeyID []byte
+		// trusted is set for certificates marked as trusted by Java.
+		trusted bool
+		// inChain is set once the certificate is part of a private key entry's chain.
+		inChain bool
+	}
+)
+
+// unpackPKCS12 decodes a password protected PKCS#12 keystore.
+// As PKCS#12 has no notion of entries, private keys are matched to certificates by local key ID & chains are
+// built by issuer. Certificates that are marked as trusted or are not part of any chain become trusted
+// certificate entries.
+func unpackPKCS12(data []byte, password string, keyPasswords map[string]string) (*Keystore, error) {
+	var pfx pfxPdu
+	if err := unmarshalDER(data, &pfx); err != nil {
+		return nil, fmt.Errorf("error unmarshalling PKCS#12 structure: %w", err)
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, fmt.Errorf("unsupported authenticated safe content type %s", pfx.AuthSafe.ContentType)
+	}
+	var authSafeData []byte
+	if err := unmarshalDER(pfx.AuthSafe.Content.Bytes, &authSafeData); err != nil {
+		return nil, fmt.Errorf("error unmarshalling authenticated safe: %w", err)
+	}
+
+	// verify MAC, stores without one are only protected by encryption
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		if err := verifyPKCS12MAC(pfx.MacData, authSafeData, password); err != nil {
+			return nil, err
+		}
+	}
+
+	// decrypt contents
+	var authSafe []contentInfo
+	if err := unmarshalDER(authSafeData, &authSafe); err != nil {
+		return nil, fmt.Errorf("error unmarshalling authenticated safe: %w", err)
+	}
+	var bags []safeBag
+	for _, ci := range authSafe {
+		var bagsData []byte
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if err := unmarshalDER(ci.Content.Bytes, &bagsData); err != nil {
+				return nil, fmt.Errorf("error unmarshalling data: %w", err)
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var ed encryptedData
+			if err := unmarshalDER(ci.Content.Bytes, &ed); err != nil {
+				return nil, fmt.Errorf("error unmarshalling encrypted data: %w", err)
+			}
+			var err error
+			info := ed.EncryptedContentInfo
+			if bagsData, err = pbeDecrypt(info.ContentEncryptionAlgorithm, info.EncryptedContent, password); err != nil {
+				return nil, fmt.Errorf("error decrypting contents: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported content type %s", ci.ContentType)
+		}
+
+		var contents []safeBag
+		if err := unmarshalDER(bagsData, &contents); err != nil {
+			return nil, fmt.Errorf("error unmarshalling bags: %w", err)
+		}
+		bags = append(bags, contents...)
+	}
+
+	return pkcs12Entries(bags, password, keyPasswords)
+}
+
+// verifyPKCS12MAC verifies the integrity MAC over the authenticated safe.
+func verifyPKCS12MAC(mac macData, authSafe []byte, password string) error {
+	var (
+		h func() hash.Hash
+		v int
+	)
+	switch algo := mac.Mac.Algorithm.Algorithm; {
+	case algo.Equal(oidSHA1):
+		h, v = sha1.New, 64
+	case algo.Equal(oidSHA256):
+		h, v = sha256.New, 64
+	case algo.Equal(oidSHA384):
+		h, v = sha512.New384, 128
+	case algo.Equal(oidSHA512):
+		h, v = sha512.New, 128
+	default:
+		return fmt.Errorf("unsupported MAC algorithm %s", algo)
+	}
+	if mac.Iterations < 1 || mac.Iterations > maxPBEIterations {
+		return fmt.Errorf("invalid MAC iteration count %d", mac.Iterations)
+	}
+
+	key := pkcs12KDF(h, v, pkcs12Password(password), mac.MacSalt, mac.Iterations, pkcs12KDFMACID, h().Size())
+	m := hmac.New(h, key)
+	m.Write(authSafe)
+	if !hmac.Equal(m.Sum(nil), mac.Mac.Digest) {
+		return ErrIntegrityCheck
+	}
+	return nil
+}
+
+// pkcs12Entries converts decrypted bags to keystore entries. Key passwords are looked up by the friendly name of
+// each key or secret bag.
+func pkcs12Entries(bags []safeBag, password string, keyPasswords map[string]string) (*Keystore, error) {
+	var (
+		keys       []*pkcs12Key
+		certs      []*pkcs12Cert
+		secretKeys []*Entry
+	)
+
+	for i, bag := range bags {
+		name, localKeyID, trusted, err := pkcs12BagAttributes(bag.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("error reading attributes of bag %d: %w", i, err)
+		}
+
+		switch {
+		case bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+			var keyInfo jks.EncryptedPrivateKeyInfo
+			if err := unmarshalDER(bag.Value.Bytes, &keyInfo); err != nil {
+				return nil, fmt.Errorf("error unmarshalling encrypted private key %d: %w", i, err)
+			}
+			plaintext, err := pbeDecrypt(keyInfo.Algo, keyInfo.EncryptedData, keyPassword(keyPasswords, name, password))
+			if err != nil {
+				return nil, fmt.Errorf("error decrypting private key %d: %w", i, err)
+			}
+			key, err := parsePKCS8(plaintext)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing private key %d: %w", i, err)
+			}
+			keys = append(keys, &pkcs12Key{key: key, name: name, localKeyID: localKeyID})
+		case bag.ID.Equal(oidKeyBag):
+			key, err := parsePKCS8Key(bag.Value.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing private key %d: %w", i, err)
+			}
+			keys = append(keys, &pkcs12Key{key: key, name: name, localKeyID: localKeyID})
+		case bag.ID.Equal(oidCertBag):
+			var cb certBag
+			if err := unmarshalDER(bag.Value.Bytes, &cb); err != nil {
+				return nil, fmt.Errorf("error unmarshalling certificate %d: %w", i, err)
+			}
+			if !cb.ID.Equal(oidX509Certificate) {
+				return nil, fmt.Errorf("unsupported certificate type %s", cb.ID)
+			}
+			cert, err := x509.ParseCertificate(cb.Data)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing certificate %d: %w", i, err)
+			}
+			certs = append(certs, &pkcs12Cert{cert: cert, name: name, localKeyID: localKeyID, trusted: trusted})
+		case bag.ID.Equal(oidSecretBag):
+			entry, err := pkcs12SecretKey(bag, name, localKeyID, keyPassword(keyPasswords, name, password))
+			if err != nil {
+				return nil, fmt.Errorf("error reading secret key %d: %w", i, err)
+			}
+			secretKeys = append(secretKeys, entry)
+		default:
+			return nil, fmt.Errorf("unsupported bag type %s", bag.ID)
+		}
+	}
+
+	ks := &Keystore{Type: StoreTypePKCS12}
+
+	// link private keys to their certificates
+	for i, key := range keys {
+		leaf := pkcs12LeafCert(key, certs)
+		if leaf == nil {
+			return nil, fmt.Errorf("no certificate found for private key %d", i)
+		}
+
+		alias := key.name
+		if alias == "" {
+			alias = leaf.name
+		}
+		if alias == "" {
+			alias = generateAlias(leaf.cert)
+		}
+
+		ks.Entries = append(ks.Entries, &Entry{
+			Alias:            alias,
+			Type:             EntryTypePrivateKey,
+			CertificateChain: pkcs12Chain(leaf, certs),
+			PrivateKey:       key.key,
+		})
+	}
+
+	ks.Entries = append(ks.Entries, secretKeys...)
+
+	// add remaining certificates as trusted certificates
+	for _, cert := range certs {
+		if cert.inChain && !cert.trusted {
+			continue
+		}
+
+		alias := cert.name
+		if alias == "" {
+			alias = generateAlias(cert.cert)
+		}
+
+		ks.Entries = append(ks.Entries, &Entry{
+			Alias:            alias,
+			Type:             EntryTypeTrustedCert,
+			CertificateChain: []*x509.Certificate{cert.cert},
+		})
+	}
+
+	return ks, nil
+}
+
+// pkcs12SecretKey decrypts a secret bag holding a secret key, as written by Java.
+// Secret keys without a friendly name are given an alias derived from their local key ID.
+func pkcs12SecretKey(bag safeBag, name string, localKeyID []byte, password string) (*Entry, error) {
+	var sb secretBag
+	if err := unmarshalDER(bag.Value.Bytes, &sb); err != nil {
+		return nil, fmt.Errorf("error unmarshalling secret bag: %w", err)
+	}
+	if !sb.ID.Equal(oidPKCS8ShroudedKeyBag) {
+		return nil, fmt.Errorf("unsupported secret type %s", sb.ID)
+	}
+	var keyInfo jks.EncryptedPrivateKeyInfo
+	if err := unmarshalDER(sb.Value, &keyInfo); err != nil {
+		return nil, fmt.Errorf("error unmarshalling encrypted secret key: %w", err)
+	}
+	plaintext, err := pbeDecrypt(keyInfo.Algo, keyInfo.EncryptedData, password)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting secret key: %w", err)
+	}
+	var info secretKeyInfo
+	if err := unmarshalDER(plaintext, &info); err != nil {
+		// an incorrect password only results in invalid padding some of the time
+		return nil, fmt.Errorf("%w: %v", ErrIntegrityCheck, err)
+	}
+
+	alias := name
+	if alias == "" {
+		alias = "secret-" + hex.EncodeToString(localKeyID)
+	}
+
+	return &Entry{
+		Alias:              alias,
+		Type:               EntryTypeSecretKey,
+		SecretKey:          info.Key,
+		SecretKeyAlgorithm: secretKeyAlgorithmName(info.Algo.Algorithm),
+	}, nil
+}
+
+// pkcs12LeafCert finds the certificate for a private key, by local key ID or by public key if the key has no ID.
+func pkcs12LeafCert(key *pkcs12Key, certs []*pkcs12Cert) *pkcs12Cert {
+	for _, cert := range certs {
+		if len(key.localKeyID) > 0 && bytes.Equal(key.localKeyID, cert.localKeyID) {
+			return cert
+		}
+	}
+	if len(key.localKeyID) > 0 {
+		return nil
+	}
+	for _, cert := range certs {
+		if publicKeyMatches(key.key, cert.cert) {
+			return cert
+		}
+	}
+	return nil
+}
+
+// pkcs12Chain builds a certificate chain from a leaf certificate, by following issuers through certs.
+func pkcs12Chain(leaf *pkcs12Cert, certs []*pkcs12Cert) []*x509.Certificate {
+	leaf.inChain = true
+	chain := []*x509.Certificate{leaf.cert}
+	used := map[*pkcs12Cert]bool{leaf: true}
+
+	for cur := leaf.cert; !isSelfIssued(cur); {
+		var issuer *pkcs12Cert
+		for _, cert := range certs {
+			if !used[cert] && issues(cert.cert, cur) {
+				issuer = cert
+				break
+			}
+		}
+		if issuer == nil {
+			break
+		}
+
+		issuer.inChain = true
+		used[issuer] = true
+		chain = append(chain, issuer.cert)
+		cur = issuer.cert
+	}
+
+	return chain
+}
+
+// pkcs12BagAttributes reads the friendly name, local key ID & Java trust attributes of a bag.
+func pkcs12BagAttributes(attrs []pkcs12Attribute) (name string, localKeyID []byte, trusted bool, err error) {
+	for _, attr := range attrs {
+		switch {
+		case attr.ID.Equal(oidFriendlyName):
+			var val asn1.RawValue
+			if _, err := asn1.Unmarshal(attr.Value.Bytes, &val); err != nil {
+				return "", nil, false, fmt.Errorf("error unmarshalling friendly name: %w", err)
+			}
+			if name, err = decodeBMPString(val.Bytes); err != nil {
+				return "", nil, false, fmt.Errorf("error decoding friendly name: %w", err)
+			}
+		case attr.ID.Equal(oidLocalKeyID):
+			if _, err := asn1.Unmarshal(attr.Value.Bytes, &localKeyID); err != nil {
+				return "", nil, false, fmt.Errorf("error unmarshalling local key ID: %w", err)
+			}
+		case attr.ID.Equal(oidJavaTrustedKeyUsage):
+			trusted = true
+		}
+	}
+	return name, localKeyID, trusted, nil
+}
+
+// decodeBMPString decodes a big-endian UTF-16 BMPString.
+func decodeBMPString(b []byte) (string, error) {
+	if len(b)%2 != 0 {
+		return "", errors.New("odd length BMPString")
+	}
+	s := make([]uint16, len(b)/2)
+	for i := range s {
+		s[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(s)), nil
+}
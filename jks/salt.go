@@ -0,0 +1,36 @@
+package jks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// salt returns n bytes of salt for protecting keystore contents.
+// When the builder is deterministic the salt is derived from the store password & inputs, so identical
+// inputs always produce identical salts. Otherwise the salt is read from crypto/rand.
+func (k *KeystoreBuilder) salt(n int, inputs ...[]byte) ([]byte, error) {
+	out := make([]byte, n)
+
+	if !k.deterministic {
+		if _, err := rand.Read(out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	// expand HMAC-SHA256(password, counter || inputs) until enough bytes are generated
+	for i, off := uint32(0), 0; off < n; i++ {
+		mac := hmac.New(sha256.New, []byte(k.password))
+		_ = binary.Write(mac, binary.BigEndian, i)
+		for _, input := range inputs {
+			// length prefix inputs to keep them unambiguous
+			_ = binary.Write(mac, binary.BigEndian, uint32(len(input)))
+			mac.Write(input)
+		}
+		off += copy(out[off:], mac.Sum(nil))
+	}
+
+	return out, nil
+}
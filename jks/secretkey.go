@@ -0,0 +1,153 @@
+package jks
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// jceksSealAlgorithm is the algorithm used by JCEKS to seal secret keys.
+const jceksSealAlgorithm = "PBEWithMD5AndTripleDES"
+
+// secretKeyAlgorithms maps the Java names of supported secret key algorithms to the OIDs used for them in PKCS#12.
+var secretKeyAlgorithms = map[string]asn1.ObjectIdentifier{
+	"AES":        {2, 16, 840, 1, 101, 3, 4, 1},
+	"DESede":     {1, 2, 840, 113549, 3, 7},
+	"HmacSHA1":   {1, 2, 840, 113549, 2, 7},
+	"HmacSHA224": {1, 2, 840, 113549, 2, 8},
+	"HmacSHA256": {1, 2, 840, 113549, 2, 9},
+	"HmacSHA384": {1, 2, 840, 113549, 2, 10},
+	"HmacSHA512": {1, 2, 840, 113549, 2, 11},
+}
+
+// secretKeyInfo is the PKCS#8 style structure Java uses for secret keys in PKCS#12 stores.
+type secretKeyInfo struct {
+	Version int
+	Algo    pkix.AlgorithmIdentifier
+	Key     []byte
+}
+
+// checkSecretKey returns an error wrapping ErrInvalidSecretKey if a secret key has an unsupported algorithm or
+// a key length that is invalid for its algorithm.
+func (s secretKey) checkSecretKey(alias string) error {
+	if _, ok := secretKeyAlgorithms[s.algorithm]; !ok {
+		names := make([]string, 0, len(secretKeyAlgorithms))
+		for name := range secretKeyAlgorithms {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%w: unsupported algorithm %q for alias %q, must be one of %s", ErrInvalidSecretKey, s.algorithm, alias, strings.Join(names, ", "))
+	}
+
+	switch n := len(s.key); {
+	case n == 0:
+		return fmt.Errorf("%w: key is empty for alias %q", ErrInvalidSecretKey, alias)
+	case s.algorithm == "AES" && n != 16 && n != 24 && n != 32:
+		return fmt.Errorf("%w: AES key for alias %q must be 16, 24 or 32 bytes, not %d", ErrInvalidSecretKey, alias, n)
+	case s.algorithm == "DESede" && n != 24:
+		return fmt.Errorf("%w: DESede key for alias %q must be 24 bytes, not %d", ErrInvalidSecretKey, alias, n)
+	}
+	return nil
+}
+
+// secretKeyAlgorithmName returns the Java name of a PKCS#12 secret key algorithm OID, or the OID itself if it is
+// not a supported algorithm, as Java does.
+func secretKeyAlgorithmName(oid asn1.ObjectIdentifier) string {
+	for name, algOID := range secretKeyAlgorithms {
+		if algOID.Equal(oid) {
+			return name
+		}
+	}
+	return oid.String()
+}
+
+// jceksSealKey seals a secret key as JCEKS does, returning a serialized SealedObjectForKeyProtector holding the
+// serialized SecretKeySpec encrypted with PBEWithMD5AndTripleDES.
+func jceksSealKey(sk *secretKeyEntry, password string, salt []byte) ([]byte, error) {
+	// serialize key
+	keyEnc := newJavaEncoder()
+	if err := keyEnc.writeObject(javaSecretKeySpecClass, sk.Algorithm, sk.Key); err != nil {
+		return nil, fmt.Errorf("error serializing secret key: %w", err)
+	}
+
+	// encrypt serialized key
+	params, err := asn1.Marshal(pbeParameter{
+		Salt:           salt,
+		IterationCount: jceksKeyProtectorIterations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling key protection parameters: %w", err)
+	}
+	ciphertext, err := jceksProtectKey(keyEnc.buf.Bytes(), password, salt, jceksKeyProtectorIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	// serialize sealed object
+	sealedEnc := newJavaEncoder()
+	if err := sealedEnc.writeObject(
+		javaSealedObjectForKeyProtectorClass,
+		params, ciphertext, jceksSealAlgorithm, jceksSealAlgorithm,
+	); err != nil {
+		return nil, fmt.Errorf("error serializing sealed key: %w", err)
+	}
+	return sealedEnc.buf.Bytes(), nil
+}
+
+// jceksUnsealKey reads a serialized sealed secret key from r & decrypts it, returning the key algorithm & material.
+func jceksUnsealKey(r *reader, password string) (string, []byte, error) {
+	sealed, err := readJavaObject(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading sealed key: %w", err)
+	}
+
+	// decrypt serialized key
+	sealAlg, err := sealed.stringField("sealAlg")
+	if err != nil {
+		return "", nil, err
+	}
+	if sealAlg != jceksSealAlgorithm {
+		return "", nil, fmt.Errorf("unsupported key sealing algorithm %q", sealAlg)
+	}
+	encodedParams, err := sealed.bytesField("encodedParams")
+	if err != nil {
+		return "", nil, err
+	}
+	ciphertext, err := sealed.bytesField("encryptedContent")
+	if err != nil {
+		return "", nil, err
+	}
+	var params pbeParameter
+	if err := unmarshalDER(encodedParams, &params); err != nil {
+		return "", nil, fmt.Errorf("error unmarshalling key protection parameters: %w", err)
+	}
+	plaintext, err := jceksUnprotectKey(ciphertext, password, params.Salt, params.IterationCount)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// deserialize key, a SecretKeySpec or a KeyRep for keys with a writeReplace method
+	key, err := readJavaObject(newReader(plaintext))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrIntegrityCheck, err)
+	}
+	algorithm, err := key.stringField("algorithm")
+	if err != nil {
+		return "", nil, err
+	}
+	switch key.class.name {
+	case javaSecretKeySpecClass.name:
+		material, err := key.bytesField("key")
+		return algorithm, material, err
+	case "java.security.KeyRep":
+		if format, _ := key.stringField("format"); format != "RAW" {
+			return "", nil, fmt.Errorf("unsupported key format %q", format)
+		}
+		material, err := key.bytesField("encoded")
+		return algorithm, material, err
+	default:
+		return "", nil, fmt.Errorf("unsupported key class %q", key.class.name)
+	}
+}
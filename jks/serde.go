@@ -4,22 +4,86 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
+	"strings"
 )
 
-// parse PEM data to a certificate.
+// parse PEM data containing a single certificate to a certificate.
 func parseCertPEM(data []byte) (*x509.Certificate, error) {
-	bl, err := decodePEM(data)
+	certs, err := ParseCertificatesPEM(data)
 	if err != nil {
 		return nil, err
 	}
-	return x509.ParseCertificate(bl.Bytes)
+	if len(certs) > 1 {
+		return nil, fmt.Errorf("expected a single certificate, found %d", len(certs))
+	}
+	return certs[0], nil
+}
+
+// ParseCertificatesPEM parses every certificate in PEM data, in order, ignoring PEM blocks of other types such as
+// private keys. Returns an error if the data contains no certificates or a certificate is invalid.
+func ParseCertificatesPEM(data []byte) ([]*x509.Certificate, error) {
+	blocks := decodeAllPEM(data, "CERTIFICATE")
+	if len(blocks) == 0 {
+		return nil, errors.New("error decoding certificate from PEM")
+	}
+
+	certs := make([]*x509.Certificate, len(blocks))
+	for i, bl := range blocks {
+		crt, err := x509.ParseCertificate(bl.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs[i] = crt
+	}
+	return certs, nil
+}
+
+// supportedKeyPEMTypes are the private key PEM block types decodeKeyPEM accepts: PKCS#8, encrypted PKCS#8, PKCS#1
+// & SEC 1.
+var supportedKeyPEMTypes = map[string]bool{
+	"PRIVATE KEY":           true,
+	"ENCRYPTED PRIVATE KEY": true,
+	"RSA PRIVATE KEY":       true,
+	"EC PRIVATE KEY":        true,
+}
+
+// decode the private key block of PEM data, skipping any other blocks such as EC PARAMETERS.
+// Returns ErrMultipleKeys if the data contains more than one private key block, and ErrUnsupportedKey if the
+// private key block is of another type, such as DSA PRIVATE KEY or OPENSSH PRIVATE KEY.
+func decodeKeyPEM(data []byte) (*pem.Block, error) {
+	var (
+		key   *pem.Block
+		types []string
+	)
+	for bl, rest := pem.Decode(data); bl != nil; bl, rest = pem.Decode(rest) {
+		if !strings.HasSuffix(bl.Type, "PRIVATE KEY") {
+			continue
+		}
+		if key == nil {
+			key = bl
+		}
+		types = append(types, bl.Type)
+	}
+
+	switch {
+	case len(types) == 0:
+		return nil, errors.New("error decoding private key from PEM")
+	case len(types) > 1:
+		return nil, fmt.Errorf("%w: found %d private key blocks (%s)", ErrMultipleKeys, len(types), strings.Join(types, ", "))
+	case !supportedKeyPEMTypes[key.Type]:
+		return nil, fmt.Errorf("%w: %s PEM blocks are not supported, use PKCS#8 (PRIVATE KEY), PKCS#1 (RSA PRIVATE KEY) or SEC 1 (EC PRIVATE KEY)", ErrUnsupportedKey, key.Type)
+	}
+	return key, nil
 }
 
-// decode PEM data to a pem block.
-func decodePEM(data []byte) (*pem.Block, error) {
-	bl, _ := pem.Decode(data)
-	if bl == nil {
-		return nil, errors.New("error decoding data from PEM")
+// decode all PEM blocks of a given type from data, ignoring blocks of any other type.
+func decodeAllPEM(data []byte, typ string) []*pem.Block {
+	blocks := make([]*pem.Block, 0)
+	for bl, rest := pem.Decode(data); bl != nil; bl, rest = pem.Decode(rest) {
+		if bl.Type == typ {
+			blocks = append(blocks, bl)
+		}
 	}
-	return bl, nil
+	return blocks
 }
@@ -1,12 +1,77 @@
 package jks
 
+import (
+	"crypto"
+	"crypto/x509"
+	"time"
+)
+
+// StoreType is a keystore file format.
+type StoreType string
+
+const (
+	// StoreTypeJKS is the Java KeyStore format.
+	StoreTypeJKS StoreType = "jks"
+	// StoreTypePKCS12 is the PKCS#12 format, the default keystore format since Java 9.
+	StoreTypePKCS12 StoreType = "pkcs12"
+	// StoreTypeJCEKS is the Java Cryptography Extension KeyStore format, with stronger key protection than JKS.
+	StoreTypeJCEKS StoreType = "jceks"
+	// StoreTypeBKS is the BouncyCastle keystore format, version 2, as used by Android.
+	StoreTypeBKS StoreType = "bks"
+	// StoreTypeBKSV1 is the BouncyCastle keystore format, version 1, for older Android releases.
+	StoreTypeBKSV1 StoreType = "bks-v1"
+	// StoreTypeUBER is the BouncyCastle UBER keystore format, a BKS store encrypted with Twofish.
+	StoreTypeUBER StoreType = "uber"
+)
+
+// SecretKeyStoreTypes are the store types that can hold secret keys.
+var SecretKeyStoreTypes = []StoreType{StoreTypePKCS12, StoreTypeJCEKS, StoreTypeBKS, StoreTypeBKSV1, StoreTypeUBER}
+
+// SupportsSecretKeys reports whether the store type can hold secret keys.
+func (s StoreType) SupportsSecretKeys() bool {
+	for _, storeType := range SecretKeyStoreTypes {
+		if s == storeType {
+			return true
+		}
+	}
+	return false
+}
+
+// EntryType is the type of a keystore entry.
+type EntryType string
+
+const (
+	// EntryTypePrivateKey is a private key with its certificate chain.
+	EntryTypePrivateKey EntryType = "private_key"
+	// EntryTypeTrustedCert is a trusted certificate, e.g. a certificate authority in a truststore.
+	EntryTypeTrustedCert EntryType = "trusted_certificate"
+	// EntryTypeSecretKey is a symmetric secret key, e.g. an AES or HMAC key.
+	EntryTypeSecretKey EntryType = "secret_key"
+)
+
 type (
 	// KeystoreBuilder provides a builder interface to generate JKS keystores.
 	KeystoreBuilder struct {
 		// keyPairs maps keypair aliases to keyPair.
 		keyPairs map[string]keyPair
+		// trustedCerts maps trusted certificate aliases to trustedCert.
+		trustedCerts map[string]trustedCert
+		// secretKeys maps secret key aliases to secretKey.
+		secretKeys map[string]secretKey
 		// password is the keystore password.
 		password string
+		// storeType is the format of the generated keystore.
+		storeType StoreType
+		// deterministic enables reproducible output, see SetDeterministic.
+		deterministic bool
+		// buildChains enables ordering of key pair certificate chains, see SetBuildChains.
+		buildChains bool
+		// minValidity is the minimum remaining validity of key pair certificates, see SetMinRemainingValidity.
+		minValidity time.Duration
+		// skipValidityChecks disables checks of key pair certificate validity periods, see SetSkipValidityChecks.
+		skipValidityChecks bool
+		// warnings holds the warnings found by the last build, see Warnings.
+		warnings []error
 	}
 
 	// keyPair represents a certificate to add to the keystore.
@@ -17,5 +82,131 @@ type (
 		cert []byte
 		// Optional slice of intermediate certs, in X.509 PEM format
 		caCerts [][]byte
+		// Optional password for an encrypted private key
+		keyPEMPassword string
+		// Optional password protecting the private key in the keystore, defaults to the store password
+		keyPassword string
+		// Optional trusted root certificates to verify the certificate chain against, in X.509 PEM format
+		trustedRoots []byte
+		// Set for key pairs added by AddKeystore, which are exempt from validity checks
+		imported bool
+	}
+
+	// trustedCert represents a trusted certificate to add to the keystore.
+	trustedCert struct {
+		// Certificate in X.509 PEM format
+		cert []byte
+		// Set for certificates added by AddKeystore
+		imported bool
+	}
+
+	// secretKey represents a symmetric key to add to the keystore.
+	secretKey struct {
+		// Java algorithm name, e.g. AES
+		algorithm string
+		// Raw key material
+		key []byte
+		// Optional password protecting the key in the keystore, defaults to the store password
+		keyPassword string
+		// Set for keys added by AddKeystore
+		imported bool
+	}
+
+	// secretKeyEntry is a secret key ready to be written to a keystore.
+	secretKeyEntry struct {
+		Alias     string
+		Timestamp time.Time
+		Algorithm string
+		Key       []byte
+	}
+)
+
+type (
+	// Keystore is the decoded contents of a keystore, see Open.
+	Keystore struct {
+		// Type is the detected format of the keystore.
+		Type StoreType
+		// Entries are the keystore entries, sorted by alias.
+		Entries []*Entry
+	}
+
+	// Entry is a single keystore entry.
+	Entry struct {
+		// Alias is the entry alias.
+		Alias string
+		// Type is the entry type.
+		Type EntryType
+		// Created is the creation date of the entry. PKCS#12 stores do not record creation dates, so this is the
+		// zero time for entries read from them.
+		Created time.Time
+		// CertificateChain is the certificate chain of a private key entry, starting with the leaf certificate,
+		// or the single certificate of a trusted certificate entry.
+		CertificateChain []*x509.Certificate
+		// PrivateKey is the decrypted private key of a private key entry, or nil for other entry types.
+		PrivateKey any
+		// SecretKey is the decrypted key material of a secret key entry, or nil for other entry types.
+		SecretKey []byte
+		// SecretKeyAlgorithm is the Java algorithm name of a secret key entry, e.g. AES.
+		SecretKeyAlgorithm string
+	}
+
+	// PrivateKeyEntry is a private key entry with its certificate chain, see Entry.PrivateKeyEntry.
+	PrivateKeyEntry struct {
+		// Alias is the entry alias.
+		Alias string
+		// Created is the creation date of the entry, or the zero time for entries read from PKCS#12 stores.
+		Created time.Time
+		// CertificateChain is the certificate chain, starting with the leaf certificate.
+		CertificateChain []*x509.Certificate
+		// PrivateKey is the decrypted private key, one of *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey.
+		PrivateKey crypto.PrivateKey
+	}
+
+	// TrustedCertEntry is a trusted certificate entry, see Entry.TrustedCertEntry.
+	TrustedCertEntry struct {
+		// Alias is the entry alias.
+		Alias string
+		// Created is the creation date of the entry, or the zero time for entries read from PKCS#12 stores.
+		Created time.Time
+		// Certificate is the trusted certificate.
+		Certificate *x509.Certificate
+	}
+
+	// SecretKeyEntry is a secret key entry, see Entry.SecretKeyEntry.
+	SecretKeyEntry struct {
+		// Alias is the entry alias.
+		Alias string
+		// Created is the creation date of the entry, or the zero time for entries read from PKCS#12 stores.
+		Created time.Time
+		// Algorithm is the Java algorithm name of the key, e.g. AES or HmacSHA256.
+		Algorithm string
+		// Key is the decrypted key material.
+		Key []byte
+	}
+
+	// CertificateInfo is metadata describing a certificate, see NewCertificateInfo.
+	CertificateInfo struct {
+		// SHA1Fingerprint is the lowercase hex SHA-1 fingerprint of the certificate.
+		SHA1Fingerprint string
+		// SHA256Fingerprint is the lowercase hex SHA-256 fingerprint of the certificate.
+		SHA256Fingerprint string
+		// Subject is the subject distinguished name.
+		Subject string
+		// Issuer is the issuer distinguished name.
+		Issuer string
+		// SerialNumber is the lowercase hex serial number.
+		SerialNumber string
+		// NotBefore is the start of the validity period.
+		NotBefore time.Time
+		// NotAfter is the end of the validity period.
+		NotAfter time.Time
+		// SubjectAlternativeNames are the subject alternative names, prefixed with their type as printed by
+		// OpenSSL, e.g. "DNS:example.com" or "IP Address:127.0.0.1".
+		SubjectAlternativeNames []string
+		// KeyAlgorithm is the public key algorithm, one of "RSA", "EC", "Ed25519", or the name of an unsupported
+		// algorithm.
+		KeyAlgorithm string
+		// KeySize is the public key size in bits, or 0 if the algorithm is unsupported.
+		KeySize int
 	}
 )
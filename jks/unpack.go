@@ -0,0 +1,373 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/lwithers/minijks/jks"
+)
+
+/*
+Open decodes a keystore, verifying its integrity & decrypting its private keys with the password.
+The format is detected automatically, and may be any of the store types supported by KeystoreBuilder.
+
+Parameters:
+
+	`data`     - Keystore, in any supported format
+	`password` - Password for keystore & private keys
+*/
+func Open(data []byte, password string) (*Keystore, error) {
+	return OpenWithKeyPasswords(data, password, nil)
+}
+
+/*
+OpenWithKeyPasswords decodes a keystore like Open, for keystores with private keys protected by passwords other
+than the store password.
+
+Parameters:
+
+	`data`         - Keystore, in any supported format
+	`password`     - Password for keystore, & private keys without a key password
+	`keyPasswords` - Passwords for private keys, by alias
+*/
+func OpenWithKeyPasswords(data []byte, password string, keyPasswords map[string]string) (*Keystore, error) {
+	var (
+		ks  *Keystore
+		err error
+	)
+
+	switch {
+	case len(data) >= 4 && binary.BigEndian.Uint32(data) == jks.MagicNumber:
+		ks, err = unpackJKS(data, password, keyPasswords, StoreTypeJKS)
+	case len(data) >= 4 && binary.BigEndian.Uint32(data) == jceksMagic:
+		ks, err = unpackJKS(data, password, keyPasswords, StoreTypeJCEKS)
+	case len(data) >= 4 && (binary.BigEndian.Uint32(data) == 1 || binary.BigEndian.Uint32(data) == 2):
+		// BouncyCastle stores start with their version number
+		ks, err = unpackBKS(data, password, keyPasswords)
+	case len(data) > 0 && data[0] == 0x30:
+		// PKCS#12 stores are an ASN.1 SEQUENCE
+		ks, err = unpackPKCS12(data, password, keyPasswords)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading keystore: %w", err)
+	}
+
+	sort.SliceStable(ks.Entries, func(i, j int) bool {
+		return ks.Entries[i].Alias < ks.Entries[j].Alias
+	})
+
+	return ks, nil
+}
+
+// Aliases returns the aliases of all entries in the keystore, in alias order.
+func (ks *Keystore) Aliases() []string {
+	aliases := make([]string, len(ks.Entries))
+	for i, entry := range ks.Entries {
+		aliases[i] = entry.Alias
+	}
+	return aliases
+}
+
+// CertificateChainPEM returns the certificate chain of the entry, in X.509 PEM format.
+func (e *Entry) CertificateChainPEM() [][]byte {
+	certs := make([][]byte, len(e.CertificateChain))
+	for i, cert := range e.CertificateChain {
+		certs[i] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return certs
+}
+
+// PrivateKeyPEM returns the private key of the entry in PKCS#8 PEM format, or nil if the entry has no private key.
+func (e *Entry) PrivateKeyPEM() ([]byte, error) {
+	if e.PrivateKey == nil {
+		return nil, nil
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(e.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// unpackJKS decodes a JKS or JCEKS keystore, which only differ in their magic number & private key protection.
+func unpackJKS(data []byte, password string, keyPasswords map[string]string, storeType StoreType) (*Keystore, error) {
+	// verify integrity digest
+	if len(data) < sha1.Size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	if !hmac.Equal(jks.ComputeDigest(body, password), digest) {
+		return nil, ErrIntegrityCheck
+	}
+
+	// read header
+	r := newReader(body)
+	r.uint32()
+	if version := r.uint32(); r.err == nil && version != jksVersion {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+	count := r.uint32()
+
+	// read entries
+	ks := &Keystore{}
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		tag := r.uint32()
+		entry := &Entry{
+			Alias:   r.str(),
+			Created: r.timestamp(),
+		}
+
+		switch tag {
+		case jksTagPrivateKey:
+			entry.Type = EntryTypePrivateKey
+			encKey := r.bytes()
+			entry.CertificateChain = r.certChain()
+			if r.err != nil {
+				break
+			}
+
+			key, err := unprotectKey(encKey, keyPassword(keyPasswords, entry.Alias, password))
+			if err != nil {
+				return nil, fmt.Errorf("error decrypting private key %q: %w", entry.Alias, err)
+			}
+			entry.PrivateKey = key
+		case jksTagTrustedCert:
+			entry.Type = EntryTypeTrustedCert
+			entry.CertificateChain = []*x509.Certificate{r.cert()}
+		case jksTagSecretKey:
+			if storeType != StoreTypeJCEKS {
+				return nil, fmt.Errorf("unsupported entry type %d for alias %q", tag, entry.Alias)
+			}
+			entry.Type = EntryTypeSecretKey
+			if r.err != nil {
+				break
+			}
+
+			algorithm, key, err := jceksUnsealKey(r, keyPassword(keyPasswords, entry.Alias, password))
+			if err != nil {
+				return nil, fmt.Errorf("error decrypting secret key %q: %w", entry.Alias, err)
+			}
+			entry.SecretKeyAlgorithm, entry.SecretKey = algorithm, key
+		default:
+			if r.err == nil {
+				return nil, fmt.Errorf("unsupported entry type %d for alias %q", tag, entry.Alias)
+			}
+		}
+
+		ks.Entries = append(ks.Entries, entry)
+	}
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	ks.Type = storeType
+	return ks, nil
+}
+
+// keyPassword returns the password for the private or secret key with the alias, defaulting to the store password.
+// Aliases are compared case-insensitively, preferring an exact match.
+func keyPassword(keyPasswords map[string]string, alias string, password string) string {
+	if keyPassword, ok := keyPasswords[alias]; ok {
+		return keyPassword
+	}
+	for keyAlias, keyPassword := range keyPasswords {
+		if NormalizeAlias(keyAlias) == NormalizeAlias(alias) {
+			return keyPassword
+		}
+	}
+	return password
+}
+
+// unprotectKey decrypts a private key protected with the JKS or JCEKS key protector.
+// encKey is a marshalled PKCS#8 EncryptedPrivateKeyInfo.
+func unprotectKey(encKey []byte, password string) (any, error) {
+	var keyInfo jks.EncryptedPrivateKeyInfo
+	if err := unmarshalDER(encKey, &keyInfo); err != nil {
+		return nil, fmt.Errorf("error unmarshalling encrypted private key: %w", err)
+	}
+
+	var plaintext []byte
+	switch algo := keyInfo.Algo.Algorithm; {
+	case algo.Equal(jks.JavaKeyEncryptionOID1):
+		var err error
+		if plaintext, err = jks.DecryptJavaKeyEncryption1(keyInfo.EncryptedData, password); err != nil {
+			return nil, ErrIntegrityCheck
+		}
+	case algo.Equal(jks.JavaKeyEncryptionOID2):
+		var params pbeParameter
+		if err := unmarshalDER(keyInfo.Algo.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("error unmarshalling key protection parameters: %w", err)
+		}
+		var err error
+		if plaintext, err = jceksUnprotectKey(keyInfo.EncryptedData, password, params.Salt, params.IterationCount); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key protection algorithm %s", algo)
+	}
+
+	return parsePKCS8(plaintext)
+}
+
+// parsePKCS8 parses a decrypted PKCS#8 private key. As the key protectors used by JCEKS & PKCS#12 have no
+// integrity check beyond padding, a key that fails to parse most likely means an incorrect password, unless
+// it is a key with an unsupported algorithm.
+func parsePKCS8(plaintext []byte) (any, error) {
+	key, err := parsePKCS8Key(plaintext)
+	if errors.Is(err, ErrUnsupportedKey) {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntegrityCheck, err)
+	}
+	return key, nil
+}
+
+// unmarshalDER unmarshals ASN.1 DER data, rejecting trailing data.
+func unmarshalDER(data []byte, val any) error {
+	rest, err := asn1.Unmarshal(data, val)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("trailing data after ASN.1 structure")
+	}
+	return nil
+}
+
+// reader decodes the big-endian binary encoding used by Java keystores. The first error is recorded & all
+// subsequent reads return zero values, so callers only need to check for errors once a structure is read.
+type reader struct {
+	r   *bytes.Reader
+	err error
+}
+
+// newReader creates a reader over data.
+func newReader(data []byte) *reader {
+	return &reader{r: bytes.NewReader(data)}
+}
+
+// read reads n bytes.
+func (r *reader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > r.r.Len() {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := make([]byte, n)
+	_, _ = r.r.Read(b)
+	return b
+}
+
+// uint8 reads a single byte.
+func (r *reader) uint8() uint8 {
+	b := r.read(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// uint32 reads a big-endian 32-bit unsigned integer.
+func (r *reader) uint32() uint32 {
+	b := r.read(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// uint64 reads a big-endian 64-bit unsigned integer.
+func (r *reader) uint64() uint64 {
+	b := r.read(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// timestamp reads a timestamp written as big-endian milliseconds since the Unix epoch.
+func (r *reader) timestamp() time.Time {
+	ms := r.uint64()
+	if r.err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(ms)).UTC()
+}
+
+// bytes reads a byte slice prefixed with its 32-bit length.
+func (r *reader) bytes() []byte {
+	return r.read(int(r.uint32()))
+}
+
+// str reads a modified UTF-8 string prefixed with its 16-bit length, as Java's DataInput.readUTF.
+func (r *reader) str() string {
+	b := r.read(2)
+	if b == nil {
+		return ""
+	}
+	b = r.read(int(binary.BigEndian.Uint16(b)))
+	if b == nil {
+		return ""
+	}
+	s, err := decodeModifiedUTF8(b)
+	if err != nil {
+		r.err = err
+		return ""
+	}
+	return s
+}
+
+// cert reads a certificate with its type header.
+func (r *reader) cert() *x509.Certificate {
+	typ := r.str()
+	der := r.bytes()
+	if r.err != nil {
+		return nil
+	}
+	if typ != jks.CertType {
+		r.err = fmt.Errorf("unsupported certificate type %q", typ)
+		return nil
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		r.err = fmt.Errorf("error parsing certificate: %w", err)
+		return nil
+	}
+	return cert
+}
+
+// certChain reads a certificate chain prefixed with its 32-bit length.
+func (r *reader) certChain() []*x509.Certificate {
+	n := r.uint32()
+	// don't preallocate, as the length is untrusted
+	var chain []*x509.Certificate
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		chain = append(chain, r.cert())
+	}
+	return chain
+}
+
+// done returns the first error encountered, or an error if any data remains unread.
+func (r *reader) done() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.r.Len() > 0 {
+		return errors.New("unexpected trailing data")
+	}
+	return nil
+}
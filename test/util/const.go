@@ -5,4 +5,15 @@ const (
 	DefaultKeytoolImage = "eclipse-temurin:17.0.8.1_1-jdk-ubi9-minimal"
 	EnvOpenSSLImage     = "OPENSSL_IMAGE"
 	DefaultOpenSSLImage = "alpine/openssl:3.1.3"
+
+	EnvBouncyCastleJar        = "BOUNCYCASTLE_JAR"
+	DefaultBouncyCastleJarURL = "https://repo1.maven.org/maven2/org/bouncycastle/bcprov-jdk18on/1.77/bcprov-jdk18on-1.77.jar"
+	BouncyCastleProviderClass = "org.bouncycastle.jce.provider.BouncyCastleProvider"
+
+	// EnvBouncyCastleJarSHA256 overrides the pinned hex SHA-256 digest of the BouncyCastle jar, e.g. for another
+	// version in $BOUNCYCASTLE_JAR.
+	EnvBouncyCastleJarSHA256 = "BOUNCYCASTLE_JAR_SHA256"
+	// DefaultBouncyCastleJarSHA256 is the hex SHA-256 digest of the jar at DefaultBouncyCastleJarURL, which must be
+	// pinned from a verified copy of the jar, e.g. checked against its PGP signature on Maven Central.
+	DefaultBouncyCastleJarSHA256 = ""
 )
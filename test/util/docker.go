@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -13,13 +14,15 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/google/uuid"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/require"
 )
 
 // runContainer runs a one-shot container & removes it after completion.
-func runContainer(ctx context.Context, t *testing.T, cli *client.Client, image string, workDir string, entrypoint ...string) {
+// Returns the output of the container, with stdout & stderr combined.
+func runContainer(ctx context.Context, t *testing.T, cli *client.Client, image string, workDir string, entrypoint ...string) []byte {
 	// working directory for container
 	const containerCwd = "/var/tmp/workspace"
 
@@ -80,10 +83,11 @@ func runContainer(ctx context.Context, t *testing.T, cli *client.Client, image s
 		case "created":
 		case "running":
 		case "exited":
+			out := containerOutput(ctx, t, cli, crResp.ID)
 			if ctr.State.ExitCode != 0 {
-				t.Fatalf("Container exit code is %d", ctr.State.ExitCode)
+				t.Fatalf("Container exit code is %d, output: %s", ctr.State.ExitCode, out)
 			}
-			return
+			return out
 		default:
 			t.Fatalf("Unexpected state for container %s: %s", crResp.ID, st)
 		}
@@ -91,6 +95,18 @@ func runContainer(ctx context.Context, t *testing.T, cli *client.Client, image s
 	}
 }
 
+// containerOutput returns the output of container with ID containerID, with stdout & stderr combined.
+func containerOutput(ctx context.Context, t *testing.T, cli *client.Client, containerID string) []byte {
+	logs, err := cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	require.NoErrorf(t, err, "It should read logs of container with ID %s", containerID)
+	defer logs.Close()
+
+	var out bytes.Buffer
+	_, err = stdcopy.StdCopy(&out, &out, logs)
+	require.NoErrorf(t, err, "It should read logs of container with ID %s", containerID)
+	return out.Bytes()
+}
+
 // removeContainer forcibly removes container with ID containerID.
 func removeContainer(t *testing.T, cli *client.Client, containerID string) {
 	err := cli.ContainerRemove(
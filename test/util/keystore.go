@@ -12,11 +12,12 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// ReadKeystore reads a JKS keystore to PEM format.
+// ReadKeystore reads a keystore of type storeType to PEM format with keytool & openssl, checking that keytool can read
+// every entry, including secret keys.
 // Returns private key, and slice of certificates.
-func ReadKeystore(ctx context.Context, t *testing.T, keyStore []byte, password string) ([]byte, [][]byte) {
+func ReadKeystore(ctx context.Context, t *testing.T, keyStore []byte, storeType, password string) ([]byte, [][]byte) {
 	const (
-		jksFile = "keystore.jks" // file containing JKS keystore
+		ksFile  = "keystore"     // file containing keystore
 		p12File = "keystore.p12" // file containing pkcs#12 store
 		pemFile = "certs.pem"    // file containing pem certs
 	)
@@ -29,17 +30,17 @@ func ReadKeystore(ctx context.Context, t *testing.T, keyStore []byte, password s
 	tmpDir := t.TempDir()
 
 	// clean files at end of function
-	defer mustCleanFiles(t, tmpDir, jksFile, p12File, pemFile)
+	defer mustCleanFiles(t, tmpDir, ksFile, p12File, pemFile)
 
 	// write keystore to file
 	require.NoError(
 		t,
-		os.WriteFile(filepath.Join(tmpDir, jksFile), keyStore, 0640),
+		os.WriteFile(filepath.Join(tmpDir, ksFile), keyStore, 0640),
 		"It should write keystore to file",
 	)
 
-	// convert keystore to pkcs#12
-	runContainer(
+	// convert keystore to pkcs#12, which fails for single entries with a warning
+	out := runContainer(
 		ctx,
 		t,
 		cli,
@@ -47,13 +48,15 @@ func ReadKeystore(ctx context.Context, t *testing.T, keyStore []byte, password s
 		tmpDir,
 		"keytool",
 		"-importkeystore",
-		"-srckeystore", jksFile,
+		"-srckeystore", ksFile,
+		"-srcstoretype", storeType,
 		"-destkeystore", p12File,
 		"-deststoretype", "pkcs12",
 		"-srcstorepass", password,
 		"-deststorepass", password,
 		"-noprompt",
 	)
+	require.Contains(t, string(out), " 0 entries failed or cancelled", "keytool should import every entry")
 
 	// convert pkcs#12 store to pem
 	runContainer(
@@ -0,0 +1,210 @@
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/require"
+)
+
+// GenerateKeystore generates a keystore of type storeType with keytool.
+// The keystore contains an EC key pair with alias "server" & a 256-bit AES secret key with alias "secret", both
+// protected by the store password.
+// Returns keystore, and the key pair certificate in PEM format.
+func GenerateKeystore(ctx context.Context, t *testing.T, storeType, password string) ([]byte, []byte) {
+	return generateKeystore(ctx, t, storeType, password, nil)
+}
+
+// GenerateBouncyCastleKeystore generates a keystore of type storeType with keytool, using the BouncyCastle security
+// provider. The provider jar is read from the path in $BOUNCYCASTLE_JAR, or downloaded from Maven Central.
+// The keystore has the same entries as GenerateKeystore.
+// Returns keystore, and the key pair certificate in PEM format.
+func GenerateBouncyCastleKeystore(ctx context.Context, t *testing.T, storeType, password string) ([]byte, []byte) {
+	return generateKeystore(ctx, t, storeType, password, bouncyCastleJar(ctx, t))
+}
+
+// generateKeystore generates a keystore with keytool. If providerJar is set, it is used as the keystore provider.
+func generateKeystore(ctx context.Context, t *testing.T, storeType, password string, providerJar []byte) ([]byte, []byte) {
+	const (
+		ksFile       = "keystore"     // file containing generated keystore
+		crtFile      = "server.crt"   // file containing exported certificate
+		providerFile = "provider.jar" // file containing security provider
+	)
+
+	// create docker client
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	require.NoError(t, err, "It should create Docker client")
+
+	// get temp working dir
+	tmpDir := t.TempDir()
+
+	// clean files at end of function
+	defer mustCleanFiles(t, tmpDir, ksFile, crtFile)
+
+	args := []string{
+		"-keystore", ksFile,
+		"-storetype", storeType,
+		"-storepass", password,
+	}
+	if providerJar != nil {
+		require.NoError(
+			t,
+			os.WriteFile(filepath.Join(tmpDir, providerFile), providerJar, 0644),
+			"It should write provider jar to file",
+		)
+		defer mustCleanFiles(t, tmpDir, providerFile)
+		args = append(args,
+			"-providerclass", BouncyCastleProviderClass,
+			"-providerpath", providerFile,
+		)
+	}
+
+	keytool := func(cmdArgs ...string) {
+		runContainer(
+			ctx,
+			t,
+			cli,
+			envOr(EnvKeytoolImage, DefaultKeytoolImage),
+			tmpDir,
+			append(append([]string{"keytool"}, cmdArgs...), args...)...,
+		)
+	}
+
+	keytool(
+		"-genkeypair",
+		"-alias", "server",
+		"-keyalg", "EC",
+		"-groupname", "secp256r1",
+		"-dname", "CN=server, O=Foo Org",
+		"-validity", "3650",
+		"-keypass", password,
+	)
+	keytool(
+		"-genseckey",
+		"-alias", "secret",
+		"-keyalg", "AES",
+		"-keysize", "256",
+		"-keypass", password,
+	)
+	keytool(
+		"-exportcert",
+		"-rfc",
+		"-alias", "server",
+		"-file", crtFile,
+	)
+
+	keyStore, err := os.ReadFile(filepath.Join(tmpDir, ksFile))
+	require.NoError(t, err, "It should read keystore")
+	crt, err := os.ReadFile(filepath.Join(tmpDir, crtFile))
+	require.NoError(t, err, "It should read certificate")
+
+	return keyStore, crt
+}
+
+// ListKeystore lists the entries of a keystore of type storeType with keytool.
+// Returns the verbose keytool listing.
+func ListKeystore(ctx context.Context, t *testing.T, keyStore []byte, storeType, password string) string {
+	return listKeystore(ctx, t, keyStore, storeType, password, nil)
+}
+
+// ListBouncyCastleKeystore lists the entries of a keystore of type storeType with keytool, using the BouncyCastle
+// security provider as for GenerateBouncyCastleKeystore.
+// Returns the verbose keytool listing.
+func ListBouncyCastleKeystore(ctx context.Context, t *testing.T, keyStore []byte, storeType, password string) string {
+	return listKeystore(ctx, t, keyStore, storeType, password, bouncyCastleJar(ctx, t))
+}
+
+// listKeystore lists the entries of a keystore with keytool. If providerJar is set, it is used as the keystore provider.
+func listKeystore(ctx context.Context, t *testing.T, keyStore []byte, storeType, password string, providerJar []byte) string {
+	const (
+		ksFile       = "keystore"     // file containing keystore
+		providerFile = "provider.jar" // file containing security provider
+	)
+
+	// create docker client
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	require.NoError(t, err, "It should create Docker client")
+
+	// get temp working dir
+	tmpDir := t.TempDir()
+
+	// clean files at end of function
+	defer mustCleanFiles(t, tmpDir, ksFile)
+
+	// write keystore to file
+	require.NoError(
+		t,
+		os.WriteFile(filepath.Join(tmpDir, ksFile), keyStore, 0640),
+		"It should write keystore to file",
+	)
+
+	args := []string{
+		"keytool",
+		"-list",
+		"-v",
+		"-keystore", ksFile,
+		"-storetype", storeType,
+		"-storepass", password,
+	}
+	if providerJar != nil {
+		require.NoError(
+			t,
+			os.WriteFile(filepath.Join(tmpDir, providerFile), providerJar, 0644),
+			"It should write provider jar to file",
+		)
+		defer mustCleanFiles(t, tmpDir, providerFile)
+		args = append(args,
+			"-providerclass", BouncyCastleProviderClass,
+			"-providerpath", providerFile,
+		)
+	}
+
+	return string(runContainer(
+		ctx,
+		t,
+		cli,
+		envOr(EnvKeytoolImage, DefaultKeytoolImage),
+		tmpDir,
+		args...,
+	))
+}
+
+// bouncyCastleJar returns the BouncyCastle provider jar, after checking it against the pinned SHA-256 digest.
+func bouncyCastleJar(ctx context.Context, t *testing.T) []byte {
+	var jar []byte
+	if path, ok := os.LookupEnv(EnvBouncyCastleJar); ok {
+		var err error
+		jar, err = os.ReadFile(path)
+		require.NoError(t, err, "It should read BouncyCastle jar")
+	} else {
+		jar = downloadBouncyCastleJar(ctx, t)
+	}
+
+	digest := envOr(EnvBouncyCastleJarSHA256, DefaultBouncyCastleJarSHA256)
+	require.NotEmptyf(t, digest, "SHA-256 digest of BouncyCastle jar should be pinned, or set in $%s", EnvBouncyCastleJarSHA256)
+	sum := sha256.Sum256(jar)
+	require.Equal(t, strings.ToLower(digest), hex.EncodeToString(sum[:]), "BouncyCastle jar should match pinned SHA-256 digest")
+	return jar
+}
+
+// downloadBouncyCastleJar downloads the BouncyCastle provider jar from Maven Central.
+func downloadBouncyCastleJar(ctx context.Context, t *testing.T) []byte {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, DefaultBouncyCastleJarURL, nil)
+	require.NoError(t, err, "It should create request")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err, "It should download BouncyCastle jar")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "It should download BouncyCastle jar")
+
+	jar, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "It should read BouncyCastle jar")
+	return jar
+}
@@ -2,6 +2,8 @@ package util
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -23,14 +25,34 @@ func NewSelfSignedCertPEM(t *testing.T) ([]byte, []byte) {
 	priv, err := rsa.GenerateKey(rand.Reader, 4096)
 	require.NoError(t, err, "It should generate key")
 
+	return NewSelfSignedCertPEMForKey(t, priv)
+}
+
+// Create a new dummy self signed cert for an existing RSA, ECDSA or Ed25519 private key.
+// Returns private key & cert, in PEM format.
+func NewSelfSignedCertPEMForKey(t *testing.T, priv interface{}) ([]byte, []byte) {
+	return newSelfSignedCertPEM(t, priv, time.Now(), time.Now().Add(time.Hour))
+}
+
+// Create a new dummy self signed cert, valid between notBefore & notAfter.
+// Returns private key & cert, in PEM format.
+func NewSelfSignedCertPEMWithValidity(t *testing.T, notBefore, notAfter time.Time) ([]byte, []byte) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "It should generate key")
+
+	return newSelfSignedCertPEM(t, priv, notBefore, notAfter)
+}
+
+// create a new dummy self signed cert for a private key, valid between notBefore & notAfter.
+func newSelfSignedCertPEM(t *testing.T, priv interface{}, notBefore, notAfter time.Time) ([]byte, []byte) {
 	// certificate template
 	crtTmpl := x509.Certificate{
 		SerialNumber: big.NewInt(1),
 		Subject: pkix.Name{
 			Organization: []string{"Foo org"},
 		},
-		NotBefore: time.Now(),
-		NotAfter:  time.Now().Add(time.Hour),
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
 
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
@@ -52,6 +74,8 @@ func publicKey(priv interface{}) interface{} {
 		return &k.PublicKey
 	case *ecdsa.PrivateKey:
 		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
 	default:
 		return nil
 	}
@@ -69,7 +93,58 @@ func pemBlockForKey(priv interface{}) *pem.Block {
 			os.Exit(2)
 		}
 		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}
+	case ed25519.PrivateKey:
+		b, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to marshal Ed25519 private key: %v", err)
+			os.Exit(2)
+		}
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: b}
 	default:
 		return nil
 	}
 }
+
+// Create a new dummy certificate chain with a leaf certificate, `intermediates` intermediate CAs & a root CA.
+// Returns the leaf private key & the chain starting with the leaf certificate & ending with the root
+// certificate, in PEM format.
+func NewCertChainPEM(t *testing.T, intermediates int) ([]byte, [][]byte) {
+	var (
+		issuerKey  *ecdsa.PrivateKey
+		issuerCert *x509.Certificate
+		chain      [][]byte
+	)
+
+	// create certificates from the root down
+	for i := intermediates + 1; i >= 0; i-- {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err, "It should generate key")
+
+		crtTmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 1)),
+			Subject: pkix.Name{
+				Organization: []string{"Foo org"},
+				CommonName:   fmt.Sprintf("cert %d", i),
+			},
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+			BasicConstraintsValid: true,
+			IsCA:                  i > 0,
+		}
+		if issuerCert == nil {
+			issuerKey, issuerCert = priv, crtTmpl
+		}
+
+		derBytes, err := x509.CreateCertificate(rand.Reader, crtTmpl, issuerCert, publicKey(priv), issuerKey)
+		require.NoError(t, err, "It should create certificate")
+		crt, err := x509.ParseCertificate(derBytes)
+		require.NoError(t, err, "It should parse certificate")
+
+		chain = append([][]byte{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})}, chain...)
+		issuerKey, issuerCert = priv, crt
+	}
+
+	// the last issuer is the leaf
+	return pem.EncodeToMemory(pemBlockForKey(issuerKey)), chain
+}